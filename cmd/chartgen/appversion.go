@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// appVersion used for new charts when neither the config nor the API provides one
+const defaultAppVersion = "v1.0.0"
+
+// fetchVersions enables the query for integration versions. The version field of
+// integrationDefinitions is not part of the documented API, so the query is opt-in
+// until it is confirmed; without it appVersion comes from the config or the existing
+// chart.
+var fetchVersions bool
+
+// fetchIntegrationVersions returns the version of every integration definition, keyed by
+// definition ID. Versions are fetched separately from the definitions so that charts can
+// still be generated when version metadata is unavailable.
+func fetchIntegrationVersions() (map[string]string, error) {
+	query := `query GetIntegrationDefinitionVersions($cursor: String) {
+    integrationDefinitions(cursor: $cursor) {
+      definitions {
+        id
+        version
+      }
+      pageInfo {
+        endCursor
+        hasNextPage
+      }
+    }
+  }`
+
+	var data struct {
+		IntegrationDefinitions struct {
+			Definitions []struct {
+				ID      string `json:"id"`
+				Version string `json:"version"`
+			} `json:"definitions"`
+			PageInfo PageInfo `json:"pageInfo"`
+		} `json:"integrationDefinitions"`
+	}
+
+	versions := make(map[string]string)
+	var cursor *string
+	for {
+		variables := make(map[string]any)
+		if cursor != nil {
+			variables["cursor"] = *cursor
+		}
+
+		if err := executeGraphQL(query, variables, &data); err != nil {
+			return nil, err
+		}
+
+		for _, def := range data.IntegrationDefinitions.Definitions {
+			if def.Version != "" {
+				versions[def.ID] = def.Version
+			}
+		}
+
+		if !data.IntegrationDefinitions.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &data.IntegrationDefinitions.PageInfo.EndCursor
+	}
+
+	return versions, nil
+}
+
+// setIntegrationVersions fills in the Version of each definition from the API when
+// fetchVersions is set. When the versions cannot be fetched, a warning is printed and
+// definitions keep their current appVersion (see getAppVersion).
+func setIntegrationVersions(definitions []IntegrationDefinition) {
+	if !fetchVersions {
+		return
+	}
+
+	versions, err := fetchIntegrationVersions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch integration versions, keeping current appVersion: %v\n", err)
+		return
+	}
+
+	for i := range definitions {
+		definitions[i].Version = versions[definitions[i].ID]
+	}
+}
+
+// appVersionSourceAnnotation is the Chart.yaml annotation recording where appVersion came
+// from. Only an appVersion that came from the API is kept when the API returns no
+// version, so removing an override from the config goes back to defaultAppVersion.
+const appVersionSourceAnnotation = "integrations.jupiterone.io/app-version-source"
+
+// appVersionSourceAPI is the appVersionSourceAnnotation value of an integration version
+// from the API
+const appVersionSourceAPI = "api"
+
+// getAppVersion returns the appVersion for an integration chart, from (in order) the
+// appVersion override in the config, the integration version from the API, the
+// appVersion of the existing chart if it came from the API, or defaultAppVersion.
+func getAppVersion(def IntegrationDefinition) string {
+	version, _ := resolveAppVersion(def)
+	return version
+}
+
+// resolveAppVersion returns the appVersion for an integration chart (see getAppVersion)
+// and whether it is the integration version from the API
+func resolveAppVersion(def IntegrationDefinition) (string, bool) {
+	if version := getOverrides(def).AppVersion; version != "" {
+		return version, false
+	}
+	if def.Version != "" {
+		return def.Version, true
+	}
+	chartName := getChartName(def)
+	if readChartAnnotation(chartName, appVersionSourceAnnotation) == appVersionSourceAPI {
+		if version := readChartYamlField(chartName, "appVersion"); version != "" {
+			return version, true
+		}
+	}
+	return defaultAppVersion, false
+}
+
+// getChartAnnotations returns the annotations of the generated Chart.yaml
+func getChartAnnotations(def IntegrationDefinition) map[string]string {
+	if _, fromAPI := resolveAppVersion(def); fromAPI {
+		return map[string]string{appVersionSourceAnnotation: appVersionSourceAPI}
+	}
+	return nil
+}
+
+// readChartAnnotation returns an annotation from the chart's existing Chart.yaml, or ""
+// if the chart or annotation doesn't exist
+func readChartAnnotation(chartName string, key string) string {
+	content, err := os.ReadFile(filepath.Join(outputDir, chartName, "Chart.yaml"))
+	if err != nil {
+		return ""
+	}
+	var chartYaml struct {
+		Annotations map[string]string `yaml:"annotations"`
+	}
+	if err := yaml.Unmarshal(content, &chartYaml); err != nil {
+		return ""
+	}
+	return chartYaml.Annotations[key]
+}
+
+// readChartYamlField returns the value of a top-level scalar field from the chart's
+// existing Chart.yaml, or "" if the chart or field doesn't exist.
+func readChartYamlField(chartName string, field string) string {
+	file, err := os.Open(filepath.Join(outputDir, chartName, "Chart.yaml"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	prefix := field + ":"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, prefix) {
+			value := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			// Remove quotes if present
+			return strings.Trim(value, "\"'")
+		}
+	}
+	return ""
+}
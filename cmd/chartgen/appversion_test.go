@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetAppVersion(t *testing.T) {
+	savedConf, savedOutputDir := chartgenConf, outputDir
+	t.Cleanup(func() { chartgenConf, outputDir = savedConf, savedOutputDir })
+
+	outputDir = t.TempDir()
+	charts := map[string]string{
+		"existing":   "apiVersion: v2\nname: existing\nversion: 1.0.4\nappVersion: \"3.1.0\"\nannotations:\n  " + appVersionSourceAnnotation + ": \"api\"\n",
+		"overridden": "apiVersion: v2\nname: overridden\nversion: 1.0.4\nappVersion: \"9.9.9\"\n",
+	}
+	for name, chartYaml := range charts {
+		if err := os.MkdirAll(filepath.Join(outputDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(outputDir, name, "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		def      IntegrationDefinition
+		override string
+		want     string
+	}{
+		{
+			name:     "config override wins",
+			def:      IntegrationDefinition{Name: "existing", Version: "2.0.0"},
+			override: "9.9.9",
+			want:     "9.9.9",
+		},
+		{
+			name: "integration version",
+			def:  IntegrationDefinition{Name: "existing", Version: "2.0.0"},
+			want: "2.0.0",
+		},
+		{
+			name: "existing chart with an integration version",
+			def:  IntegrationDefinition{Name: "existing"},
+			want: "3.1.0",
+		},
+		{
+			name: "existing chart with a removed override",
+			def:  IntegrationDefinition{Name: "overridden"},
+			want: defaultAppVersion,
+		},
+		{
+			name: "new chart",
+			def:  IntegrationDefinition{Name: "new"},
+			want: defaultAppVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartgenConf = ChartgenConfig{}
+			if tt.override != "" {
+				chartgenConf.Integrations = map[string]IntegrationOverrides{tt.def.Name: {AppVersion: tt.override}}
+			}
+			if got := getAppVersion(tt.def); got != tt.want {
+				t.Errorf("getAppVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateChartAppVersion(t *testing.T) {
+	setupWriteTest(t, false)
+	savedWrite, savedConf := write, chartgenConf
+	t.Cleanup(func() { write, chartgenConf = savedWrite, savedConf })
+	write = true
+
+	def := testDefinition()
+	steps := []struct {
+		name     string
+		version  string
+		override string
+		want     string
+	}{
+		{name: "override", override: "9.9.9", want: "9.9.9"},
+		{name: "override removed", want: defaultAppVersion},
+		{name: "integration version", version: "2.0.0", want: "2.0.0"},
+		{name: "integration version unavailable", want: "2.0.0"},
+		{name: "override of an integration version", version: "2.0.0", override: "9.9.9", want: "9.9.9"},
+		{name: "override removed again", want: defaultAppVersion},
+	}
+
+	for _, step := range steps {
+		chartgenConf = ChartgenConfig{}
+		if step.override != "" {
+			chartgenConf.Integrations = map[string]IntegrationOverrides{def.Name: {AppVersion: step.override}}
+		}
+		def.Version = step.version
+		if err, _ := generateChart(def, &ChartReport{}); err != nil {
+			t.Fatalf("%s: generateChart: %v", step.name, err)
+		}
+		if got := readChartYamlField(def.Name, "appVersion"); got != step.want {
+			t.Errorf("%s: appVersion = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func TestSetIntegrationVersions(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"data": {"integrationDefinitions": {
+			"definitions": [{"id": "a", "version": "1.2.3"}, {"id": "b", "version": ""}],
+			"pageInfo": {"hasNextPage": false}
+		}}}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("J1_GRAPHQL_ENDPOINT", server.URL)
+
+	saved := fetchVersions
+	t.Cleanup(func() { fetchVersions = saved })
+
+	tests := []struct {
+		name         string
+		fetch        bool
+		wantVersions []string
+		wantRequests int
+	}{
+		{name: "disabled", fetch: false, wantVersions: []string{"", ""}, wantRequests: 0},
+		{name: "enabled", fetch: true, wantVersions: []string{"1.2.3", ""}, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			fetchVersions = tt.fetch
+
+			definitions := []IntegrationDefinition{{ID: "a"}, {ID: "b"}}
+			setIntegrationVersions(definitions)
+
+			for i, want := range tt.wantVersions {
+				if got := definitions[i].Version; got != want {
+					t.Errorf("Version of %s = %q, want %q", definitions[i].ID, got, want)
+				}
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// update rewrites the golden files under testdata instead of comparing against them:
+//
+//	go test ./cmd/chartgen -update
+var update = flag.Bool("update", false, "update golden files")
+
+// testDefinition returns a collector integration with a required config field, an
+// optional number field and a token auth section
+func testDefinition() IntegrationDefinition {
+	return IntegrationDefinition{
+		ID:    "test-id",
+		Name:  "example",
+		Title: "Example",
+		IntegrationPlatformFeatures: IntegrationPlatformFeatures{
+			SupportsCollectors: true,
+		},
+		ConfigFields: []ConfigField{
+			{Key: "organization", DisplayName: "Organization", Type: "string"},
+			{Key: "maxPages", DisplayName: "Max pages", Type: "number", Optional: true},
+		},
+		AuthSections: []AuthSection{
+			{
+				ID:          "token",
+				DisplayName: "Token",
+				ConfigFields: []ConfigField{
+					{Key: "apiToken", DisplayName: "API token", Type: "string", Mask: true},
+				},
+			},
+		},
+	}
+}
+
+// generateTestChart writes the chart of a definition to a temporary output directory
+// and returns the chart directory
+func generateTestChart(t *testing.T, def IntegrationDefinition) string {
+	t.Helper()
+
+	chartDir, _ := generateTestChartReport(t, def)
+	return chartDir
+}
+
+// generateTestChartReport is generateTestChart that also returns the chart's report
+func generateTestChartReport(t *testing.T, def IntegrationDefinition) (string, ChartReport) {
+	t.Helper()
+
+	savedOutputDir, savedWrite, savedProgress := outputDir, write, progress
+	t.Cleanup(func() {
+		outputDir, write, progress = savedOutputDir, savedWrite, savedProgress
+	})
+	outputDir = t.TempDir()
+	write = true
+	progress = io.Discard
+
+	var result ChartReport
+	if err, _ := generateChart(def, &result); err != nil {
+		t.Fatalf("generateChart: %v", err)
+	}
+	return filepath.Join(outputDir, getChartName(def)), result
+}
+
+// renderOptions changes how renderTestChartWith renders a chart
+type renderOptions struct {
+	// skipSchema renders as --skip-schema-validation does, to test the render-time
+	// checks behind the schema
+	skipSchema bool
+	// apiVersions are added to the cluster's capabilities, as the CRDs of a cluster
+	// would be. lookup finds no objects when rendering.
+	apiVersions []string
+}
+
+// renderTestChart renders a chart with the Helm engine as "helm template" would, with
+// values validated against values.schema.json. It returns the manifests of the given
+// templates (all templates when none are given) that hold more than comments, each
+// under a "# Source:" line, or the error of a failed render.
+func renderTestChart(t *testing.T, chartDir string, values string, templates ...string) string {
+	t.Helper()
+	return renderTestChartWith(t, chartDir, values, renderOptions{}, templates...)
+}
+
+// renderTestChartWithoutSchema is renderTestChart with --skip-schema-validation
+func renderTestChartWithoutSchema(t *testing.T, chartDir string, values string, templates ...string) string {
+	t.Helper()
+	return renderTestChartWith(t, chartDir, values, renderOptions{skipSchema: true}, templates...)
+}
+
+// renderTestChartWith is renderTestChart with options
+func renderTestChartWith(t *testing.T, chartDir string, values string, opts renderOptions, templates ...string) string {
+	t.Helper()
+
+	chrt, err := loader.Load(chartDir)
+	if err != nil {
+		t.Fatalf("failed to load chart: %v", err)
+	}
+	vals, err := chartutil.ReadValues([]byte(values))
+	if err != nil {
+		t.Fatalf("failed to parse values: %v", err)
+	}
+	// Enable and import the values of subcharts, as helm install does
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+		t.Fatalf("failed to process dependencies: %v", err)
+	}
+
+	options := chartutil.ReleaseOptions{Name: "release", Namespace: "integrations", IsInstall: true}
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.APIVersions = append(append(chartutil.VersionSet{}, caps.APIVersions...), opts.apiVersions...)
+	renderValues, err := chartutil.ToRenderValuesWithSchemaValidation(chrt, vals, options, caps, opts.skipSchema)
+	if err != nil {
+		return "Error: " + err.Error() + "\n"
+	}
+	manifests, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return "Error: " + err.Error() + "\n"
+	}
+
+	prefix := chrt.Name() + "/templates/"
+	var names []string
+	for name, manifest := range manifests {
+		if isCommentOnly(manifest) {
+			continue
+		}
+		if len(templates) > 0 && !slices.Contains(templates, strings.TrimPrefix(name, prefix)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString("# Source: " + name + "\n")
+		b.WriteString(strings.TrimSpace(manifests[name]) + "\n")
+	}
+	return b.String()
+}
+
+// isCommentOnly reports whether a manifest holds nothing but comments, like the
+// templates of a disabled feature that only render the generated header
+func isCommentOnly(manifest string) bool {
+	for _, line := range strings.Split(manifest, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// assertGolden compares got with testdata/<test name>.golden
+func assertGolden(t *testing.T, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("rendered output differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestRenderInstances(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name: "single",
+			values: `
+organization: acme
+secret:
+  selectedAuthType: token
+  apiToken: secret-token
+`,
+		},
+		{
+			name: "merged",
+			values: `
+organization: acme
+pollingInterval: ONE_DAY
+commonLabels:
+  team: security
+secret:
+  selectedAuthType: token
+  apiToken: shared-token
+instances:
+  - name: example-a
+  - name: example-b
+    organization: other
+    pollingInterval: ONE_HOUR
+    secretName: custom-secret
+    secret:
+      apiToken: other-token
+`,
+		},
+		{
+			name: "missing name",
+			values: `
+organization: acme
+instances:
+  - name: example-a
+  - organization: other
+`,
+		},
+		{
+			name: "duplicate name",
+			values: `
+organization: acme
+instances:
+  - name: example-a
+  - name: example-a
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, renderTestChart(t, chartDir, tt.values, "integrationinstance.yaml", "secret.yaml"))
+		})
+	}
+}
+
+var checksumPattern = regexp.MustCompile(`checksum/secret: ([0-9a-f]{64})`)
+
+func TestRenderSecretChecksum(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	checksum := func(t *testing.T, values string) string {
+		t.Helper()
+		manifest := renderTestChart(t, chartDir, "organization: acme\n"+values, "integrationinstance.yaml")
+		match := checksumPattern.FindStringSubmatch(manifest)
+		if match == nil {
+			t.Fatalf("no checksum/secret annotation in:\n%s", manifest)
+		}
+		return match[1]
+	}
+
+	base := `
+secret:
+  selectedAuthType: token
+  apiToken: token
+`
+	tests := []struct {
+		name        string
+		values      string
+		wantChanged bool
+	}{
+		{
+			name:   "same values",
+			values: base,
+		},
+		{
+			name: "config change",
+			values: base + `
+maxPages: 10
+pollingInterval: ONE_DAY
+`,
+		},
+		{
+			name: "credential change",
+			values: `
+secret:
+  selectedAuthType: token
+  apiToken: rotated
+`,
+			wantChanged: true,
+		},
+		{
+			name:        "secret name change",
+			values:      base + "secretName: other-secret\n",
+			wantChanged: true,
+		},
+		{
+			name:        "rotation nonce",
+			values:      base + "secretRotationNonce: \"2026-10-19\"\n",
+			wantChanged: true,
+		},
+		{
+			name: "secret backend change",
+			values: base + `
+secretBackend:
+  provider: vault
+  vault:
+    mount: kv
+    path: jupiterone/example
+`,
+			wantChanged: true,
+		},
+	}
+
+	want := checksum(t, base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if changed := checksum(t, tt.values) != want; changed != tt.wantChanged {
+				t.Errorf("checksum changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+
+	// With an external secret only the name and the nonce are known
+	external := "createSecret: false\n"
+	t.Run("external secret credential change", func(t *testing.T) {
+		if checksum(t, external+base) != checksum(t, external+"secret:\n  apiToken: rotated\n") {
+			t.Error("checksum of an external secret changed with the values under secret")
+		}
+	})
+	t.Run("external secret rotation nonce", func(t *testing.T) {
+		if checksum(t, external) == checksum(t, external+"secretRotationNonce: \"2\"\n") {
+			t.Error("checksum of an external secret did not change with secretRotationNonce")
+		}
+	})
+}
+
+func TestRenderWithoutSecretFields(t *testing.T) {
+	def := testDefinition()
+	def.AuthSections = nil
+	chartDir := generateTestChart(t, def)
+
+	values := `
+organization: acme
+commonAnnotations:
+  owner: security@example.com
+`
+	assertGolden(t, renderTestChart(t, chartDir, values, "integrationinstance.yaml"))
+}
+
+func TestRenderMetadata(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name:   "defaults",
+			values: ``,
+		},
+		{
+			name: "name override",
+			values: `
+nameOverride: github
+`,
+		},
+		{
+			name: "fullname override",
+			values: `
+nameOverride: github
+fullnameOverride: github-production
+`,
+		},
+		{
+			name: "fullname override truncated",
+			values: `
+fullnameOverride: github-production-organization-with-a-very-long-name-that-ends-in-a-dash-
+`,
+		},
+		{
+			name: "common labels and annotations",
+			values: `
+commonLabels:
+  team: security
+  app.kubernetes.io/part-of: jupiterone
+commonAnnotations:
+  owner: security@example.com
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values + "organization: acme\nsecret:\n  selectedAuthType: token\n  apiToken: token\n"
+			assertGolden(t, renderTestChart(t, chartDir, values, "integrationinstance.yaml", "secret.yaml"))
+		})
+	}
+}
+
+// runnerAPIVersion makes the IntegrationRunner CRD available when rendering
+const runnerAPIVersion = "integrations.jupiterone.io/v1/IntegrationRunner"
+
+func TestRenderRunner(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	tests := []struct {
+		name     string
+		values   string
+		withCRDs bool
+	}{
+		{
+			// helm template without a cluster cannot look the runner up
+			name:   "without CRDs",
+			values: ``,
+		},
+		{
+			name:     "runner missing",
+			values:   ``,
+			withCRDs: true,
+		},
+		{
+			name: "runner missing with check fail",
+			values: `
+runner:
+  check: fail
+`,
+			withCRDs: true,
+		},
+		{
+			name: "runner missing with check ignore",
+			values: `
+runner:
+  check: ignore
+`,
+			withCRDs: true,
+		},
+		{
+			name: "created runner",
+			values: `
+collectorName: collector
+runner:
+  check: fail
+  create: true
+  accountId: j1-account
+  apiToken: account-token
+commonAnnotations:
+  owner: security@example.com
+`,
+			withCRDs: true,
+		},
+		{
+			name: "created runner missing account",
+			values: `
+runner:
+  create: true
+`,
+		},
+		{
+			name: "instance using another runner",
+			values: `
+runner:
+  create: true
+  accountId: j1-account
+instances:
+  - name: example-a
+  - name: example-b
+    collectorName: other
+`,
+			withCRDs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts renderOptions
+			if tt.withCRDs {
+				opts.apiVersions = []string{runnerAPIVersion}
+			}
+			values := tt.values + "organization: acme\nsecret:\n  selectedAuthType: token\n  apiToken: token\n"
+			assertGolden(t, renderTestChartWith(t, chartDir, values, opts, "NOTES.txt", "runner.yaml"))
+		})
+	}
+}
+
+func TestRenderTestHook(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name:   "single instance",
+			values: ``,
+		},
+		{
+			name: "instances",
+			values: `
+test:
+  timeoutSeconds: 60
+  image: registry.example.com/kubectl:1.34
+instances:
+  - name: example-a
+  - name: example-b
+`,
+		},
+		{
+			name: "disabled",
+			values: `
+test:
+  enabled: false
+`,
+		},
+		{
+			name: "invalid timeout",
+			values: `
+test:
+  timeoutSeconds: 0
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values + "organization: acme\nsecret:\n  apiToken: token\n"
+			assertGolden(t, renderTestChart(t, chartDir, values, "tests/integrationinstance-test.yaml"))
+		})
+	}
+}
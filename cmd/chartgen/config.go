@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default path of the repository-level chartgen configuration file
+const defaultConfigPath = "chartgen.yaml"
+
+// ChartgenConfig is the repository-level chartgen.yaml. Include and exclude select the
+// integrations to generate. Overrides under defaults apply to every integration; entries
+// under integrations (keyed by integration definition name) are merged on top of them.
+type ChartgenConfig struct {
+	// Glob patterns selecting the integrations to generate (all if empty) and to skip
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	Defaults     IntegrationOverrides            `yaml:"defaults"`
+	Integrations map[string]IntegrationOverrides `yaml:"integrations"`
+}
+
+// IntegrationOverrides customizes the chart generated for an integration
+type IntegrationOverrides struct {
+	ChartName     string                   `yaml:"chartName"`
+	AppVersion    string                   `yaml:"appVersion"`
+	Chart         ChartMetadata            `yaml:"chart"`
+	ValuesComment string                   `yaml:"valuesComment"`
+	Fields        map[string]FieldOverride `yaml:"fields"`
+}
+
+// ChartMetadata holds optional Chart.yaml fields that the API does not provide
+type ChartMetadata struct {
+	KubeVersion string       `yaml:"kubeVersion"`
+	Home        string       `yaml:"home"`
+	Icon        string       `yaml:"icon"`
+	Keywords    []string     `yaml:"keywords"`
+	Sources     []string     `yaml:"sources"`
+	Maintainers []Maintainer `yaml:"maintainers"`
+}
+
+type Maintainer struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	URL   string `yaml:"url"`
+}
+
+// FieldOverride changes how a single config or auth field is presented
+type FieldOverride struct {
+	Default    any    `yaml:"default"`
+	Hidden     bool   `yaml:"hidden"`
+	Deprecated string `yaml:"deprecated"`
+	Comment    string `yaml:"comment"`
+}
+
+var (
+	configPath   string
+	chartgenConf ChartgenConfig
+)
+
+// loadConfig reads the chartgen configuration file. A missing file is only an error
+// when the path was given explicitly.
+func loadConfig(path string, explicit bool) (ChartgenConfig, error) {
+	var conf ChartgenConfig
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return conf, nil
+		}
+		return conf, fmt.Errorf("failed to open config %s: %w", path, err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&conf); err != nil && !errors.Is(err, io.EOF) {
+		return conf, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if conf.Defaults.ChartName != "" {
+		return conf, fmt.Errorf("invalid config %s: chartName can only be set per integration", path)
+	}
+
+	return conf, nil
+}
+
+// getOverrides returns the merged defaults and per-integration overrides for an integration
+func getOverrides(def IntegrationDefinition) IntegrationOverrides {
+	merged := chartgenConf.Defaults
+	specific, ok := chartgenConf.Integrations[def.Name]
+	if !ok {
+		return merged
+	}
+
+	merged.ChartName = specific.ChartName
+	if specific.AppVersion != "" {
+		merged.AppVersion = specific.AppVersion
+	}
+	if specific.Chart.KubeVersion != "" {
+		merged.Chart.KubeVersion = specific.Chart.KubeVersion
+	}
+	if specific.Chart.Home != "" {
+		merged.Chart.Home = specific.Chart.Home
+	}
+	if specific.Chart.Icon != "" {
+		merged.Chart.Icon = specific.Chart.Icon
+	}
+	if len(specific.Chart.Keywords) > 0 {
+		merged.Chart.Keywords = specific.Chart.Keywords
+	}
+	if len(specific.Chart.Sources) > 0 {
+		merged.Chart.Sources = specific.Chart.Sources
+	}
+	if len(specific.Chart.Maintainers) > 0 {
+		merged.Chart.Maintainers = specific.Chart.Maintainers
+	}
+	if specific.ValuesComment != "" {
+		merged.ValuesComment = specific.ValuesComment
+	}
+
+	fields := make(map[string]FieldOverride)
+	for key, field := range chartgenConf.Defaults.Fields {
+		fields[key] = field
+	}
+	for key, field := range specific.Fields {
+		fields[key] = field
+	}
+	merged.Fields = fields
+
+	return merged
+}
+
+// applyFieldOverrides returns a copy of the definition with the configured field
+// overrides applied to every config field, config section and auth section field.
+// Overrides for keys that the definition does not have are reported as warnings.
+func applyFieldOverrides(def IntegrationDefinition) IntegrationDefinition {
+	overrides := getOverrides(def).Fields
+	if len(overrides) == 0 {
+		return def
+	}
+
+	used := make(map[string]bool)
+	def.ConfigFields = overrideFields(def.ConfigFields, overrides, used)
+
+	sections := make([]ConfigSection, len(def.ConfigSections))
+	for i, cs := range def.ConfigSections {
+		cs.ConfigFields = overrideFields(cs.ConfigFields, overrides, used)
+		sections[i] = cs
+	}
+	def.ConfigSections = sections
+
+	authSections := make([]AuthSection, len(def.AuthSections))
+	for i, as := range def.AuthSections {
+		as.ConfigFields = overrideFields(as.ConfigFields, overrides, used)
+		authSections[i] = as
+	}
+	def.AuthSections = authSections
+
+	var unused []string
+	for key := range chartgenConf.Integrations[def.Name].Fields {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		fmt.Fprintf(os.Stderr, "Warning: %s: override for unknown field %q in %s\n", def.Name, key, configPath)
+	}
+
+	return def
+}
+
+func overrideFields(fields []ConfigField, overrides map[string]FieldOverride, used map[string]bool) []ConfigField {
+	if fields == nil {
+		return nil
+	}
+
+	result := make([]ConfigField, len(fields))
+	for i, cf := range fields {
+		if override, ok := overrides[cf.Key]; ok {
+			used[cf.Key] = true
+			if override.Default != nil {
+				cf.DefaultValue = override.Default
+			}
+			cf.Hidden = override.Hidden
+			cf.Deprecated = override.Deprecated
+			cf.Comment = override.Comment
+		}
+		cf.ConfigFields = overrideFields(cf.ConfigFields, overrides, used)
+		result[i] = cf
+	}
+	return result
+}
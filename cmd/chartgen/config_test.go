@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string // no file is written when empty
+		explicit bool
+		wantErr  string
+		check    func(t *testing.T, conf ChartgenConfig)
+	}{
+		{
+			name: "missing default file",
+		},
+		{
+			name:     "missing explicit file",
+			explicit: true,
+			wantErr:  "failed to open config",
+		},
+		{
+			name:    "empty file",
+			content: "# no overrides yet\n",
+		},
+		{
+			name: "overrides",
+			content: `
+include: ["git*"]
+exclude: [gitlab]
+defaults:
+  chart:
+    home: https://example.com
+integrations:
+  github:
+    chartName: github-cloud
+    appVersion: "2.4.1"
+    fields:
+      organization:
+        default: acme
+        comment: The GitHub organization
+`,
+			check: func(t *testing.T, conf ChartgenConfig) {
+				if got := strings.Join(conf.Include, ","); got != "git*" {
+					t.Errorf("Include = %q, want %q", got, "git*")
+				}
+				github := conf.Integrations["github"]
+				if github.ChartName != "github-cloud" || github.AppVersion != "2.4.1" {
+					t.Errorf("github overrides = %+v", github)
+				}
+				if got := github.Fields["organization"].Default; got != "acme" {
+					t.Errorf("organization default = %v, want acme", got)
+				}
+				if conf.Defaults.Chart.Home != "https://example.com" {
+					t.Errorf("defaults.chart.home = %q", conf.Defaults.Chart.Home)
+				}
+			},
+		},
+		{
+			name: "unknown top-level key",
+			content: `
+integration:
+  github:
+    chartName: github-cloud
+`,
+			wantErr: "field integration not found",
+		},
+		{
+			name: "unknown field override key",
+			content: `
+integrations:
+  github:
+    fields:
+      organization:
+        defualt: acme
+`,
+			wantErr: "field defualt not found",
+		},
+		{
+			name: "wrong type",
+			content: `
+include: github
+`,
+			wantErr: "failed to parse config",
+		},
+		{
+			name: "chartName in defaults",
+			content: `
+defaults:
+  chartName: shared
+`,
+			wantErr: "chartName can only be set per integration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chartgen.yaml")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			conf, err := loadConfig(path, tt.explicit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, conf)
+			}
+		})
+	}
+}
+
+func TestGetOverrides(t *testing.T) {
+	saved := chartgenConf
+	t.Cleanup(func() { chartgenConf = saved })
+
+	chartgenConf = ChartgenConfig{
+		Defaults: IntegrationOverrides{
+			AppVersion: "1.0.0",
+			Chart:      ChartMetadata{Home: "https://example.com", Keywords: []string{"jupiterone"}},
+			Fields:     map[string]FieldOverride{"organization": {Comment: "default comment"}, "token": {Hidden: true}},
+		},
+		Integrations: map[string]IntegrationOverrides{
+			"github": {
+				ChartName: "github-cloud",
+				Chart:     ChartMetadata{Keywords: []string{"github"}},
+				Fields:    map[string]FieldOverride{"organization": {Comment: "GitHub organization"}},
+			},
+		},
+	}
+
+	github := getOverrides(IntegrationDefinition{Name: "github"})
+	if github.ChartName != "github-cloud" {
+		t.Errorf("ChartName = %q, want github-cloud", github.ChartName)
+	}
+	if github.AppVersion != "1.0.0" || github.Chart.Home != "https://example.com" {
+		t.Errorf("defaults not inherited: %+v", github)
+	}
+	if got := strings.Join(github.Chart.Keywords, ","); got != "github" {
+		t.Errorf("Keywords = %q, want github", got)
+	}
+	if got := github.Fields["organization"].Comment; got != "GitHub organization" {
+		t.Errorf("organization comment = %q", got)
+	}
+	if !github.Fields["token"].Hidden {
+		t.Errorf("token override from defaults not inherited")
+	}
+
+	other := getOverrides(IntegrationDefinition{Name: "jira"})
+	if other.ChartName != "" || other.Fields["organization"].Comment != "default comment" {
+		t.Errorf("jira overrides = %+v, want the defaults", other)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fieldKind is where a field's value lives in the generated chart
+type fieldKind int
+
+const (
+	// Non-masked config field: a top-level value rendered into spec.config
+	fieldConfig fieldKind = iota
+	// Masked config field: a key under secret and in the Secret
+	fieldMasked
+	// Auth section field: a key under secret and in the Secret
+	fieldAuth
+)
+
+func (k fieldKind) String() string {
+	switch k {
+	case fieldMasked:
+		return "masked config field"
+	case fieldAuth:
+		return "auth field"
+	default:
+		return "config field"
+	}
+}
+
+// isSecret returns true if fields of this kind are stored in the Secret
+func (k fieldKind) isSecret() bool {
+	return k != fieldConfig
+}
+
+// reservedValuesKeys are the top-level values used by the chart itself, which
+// non-masked config fields must not shadow. "name" is reserved for instances entries.
+var reservedValuesKeys = []string{
+	"collectorName",
+	"commonAnnotations",
+	"commonLabels",
+	"createSecret",
+	"fullnameOverride",
+	"instances",
+	"integrationInstanceId",
+	"name",
+	"nameOverride",
+	"pollingInterval",
+	"pollingIntervalCron",
+	"resourceGroupId",
+	"runner",
+	"secret",
+	"secretBackend",
+	"secretName",
+	"secretRotationNonce",
+	"test",
+}
+
+// reservedSecretKeys are keys of the generated Secret that fields must not use
+var reservedSecretKeys = []string{
+	"selectedAuthType",
+}
+
+// secretSettingKeys are values under secret that configure the generated Secret
+// rather than hold one of its keys. Fields must not use them.
+var secretSettingKeys = []string{
+	"annotations",
+	"immutable",
+	"keepOnUninstall",
+	"labels",
+	"preEncoded",
+}
+
+// fieldKeyPattern matches keys that can be referenced as .Values.<key> in templates
+var fieldKeyPattern = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// registeredField is a field together with where it was first defined
+type registeredField struct {
+	Field  ConfigField
+	Kind   fieldKind
+	Source string
+}
+
+// fieldRegistry holds every config and auth field of a definition, keyed by field key.
+// Fields are kept in definition order: configFields, configSections, then authSections.
+type fieldRegistry struct {
+	Fields   []registeredField
+	Problems []string
+	Warnings []string
+}
+
+// newFieldRegistry builds the field registry for a definition. A key defined more than
+// once with the same kind is merged, keeping the first definition; auth sections are
+// alternatives, so the same key in several of them is expected. A key defined with
+// different kinds (e.g. both masked and non-masked) or a key that cannot be used in
+// templates is recorded in Problems. A field with a key reserved by the chart is left
+// out of the registry and recorded in Warnings, so the rest of the chart is still
+// generated.
+func newFieldRegistry(def IntegrationDefinition) fieldRegistry {
+	var reg fieldRegistry
+	index := make(map[string]int)
+
+	add := func(fields []ConfigField, source string, kindOf func(ConfigField) fieldKind) {
+		for _, cf := range flattenConfigFields(fields, make(map[string]bool)) {
+			kind := kindOf(cf)
+			if i, ok := index[cf.Key]; ok {
+				existing := reg.Fields[i]
+				if existing.Kind != kind {
+					reg.Problems = append(reg.Problems, fmt.Sprintf("key %q is defined as %s in %s and as %s in %s", cf.Key, existing.Kind, existing.Source, kind, source))
+				}
+				continue
+			}
+			index[cf.Key] = len(reg.Fields)
+			reg.Fields = append(reg.Fields, registeredField{Field: cf, Kind: kind, Source: source})
+		}
+	}
+
+	configKind := func(cf ConfigField) fieldKind {
+		if cf.Mask {
+			return fieldMasked
+		}
+		return fieldConfig
+	}
+	add(def.ConfigFields, "configFields", configKind)
+	for _, cs := range def.ConfigSections {
+		add(cs.ConfigFields, fmt.Sprintf("configSection %q", cs.DisplayName), configKind)
+	}
+	for _, as := range def.AuthSections {
+		add(as.ConfigFields, fmt.Sprintf("authSection %q", as.ID), func(ConfigField) fieldKind { return fieldAuth })
+	}
+
+	fields := reg.Fields[:0]
+	for _, rf := range reg.Fields {
+		key := rf.Field.Key
+		reserved := ""
+		switch {
+		case !fieldKeyPattern.MatchString(key):
+			reg.Problems = append(reg.Problems, fmt.Sprintf("key %q in %s is not a valid values key", key, rf.Source))
+		case !rf.Kind.isSecret() && containsString(reservedValuesKeys, key):
+			reserved = "a chart value"
+		case rf.Kind.isSecret() && containsString(reservedSecretKeys, key):
+			reserved = "a key of the Secret"
+		case rf.Kind.isSecret() && containsString(secretSettingKeys, key):
+			reserved = "a secret setting"
+		}
+		if reserved != "" {
+			reg.Warnings = append(reg.Warnings, fmt.Sprintf("%s %q in %s is skipped because the key is reserved for %s", rf.Kind, key, rf.Source, reserved))
+			continue
+		}
+		fields = append(fields, rf)
+	}
+	reg.Fields = fields
+
+	sort.Strings(reg.Problems)
+	sort.Strings(reg.Warnings)
+	return reg
+}
+
+// Err returns an error describing every problem found, or nil
+func (r fieldRegistry) Err() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("conflicting field keys:\n  %s", strings.Join(r.Problems, "\n  "))
+}
+
+// fieldsOf returns the fields of the given kinds in registry order
+func (r fieldRegistry) fieldsOf(kinds ...fieldKind) []ConfigField {
+	var fields []ConfigField
+	for _, rf := range r.Fields {
+		for _, kind := range kinds {
+			if rf.Kind == kind {
+				fields = append(fields, rf.Field)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFieldRegistry(t *testing.T) {
+	field := func(key string) ConfigField {
+		return ConfigField{Key: key, Type: "string"}
+	}
+	masked := func(key string) ConfigField {
+		return ConfigField{Key: key, Type: "string", Mask: true}
+	}
+
+	tests := []struct {
+		name         string
+		def          IntegrationDefinition
+		wantFields   []string // "<key> <kind> (<source>)"
+		wantProblems []string
+		wantWarnings []string
+	}{
+		{
+			name: "fields of every kind",
+			def: IntegrationDefinition{
+				ConfigFields:   []ConfigField{field("host"), masked("password")},
+				ConfigSections: []ConfigSection{{DisplayName: "Advanced", ConfigFields: []ConfigField{field("timeout")}}},
+				AuthSections:   []AuthSection{{ID: "token", ConfigFields: []ConfigField{field("apiToken")}}},
+			},
+			wantFields: []string{
+				"host config field (configFields)",
+				"password masked config field (configFields)",
+				`timeout config field (configSection "Advanced")`,
+				`apiToken auth field (authSection "token")`,
+			},
+		},
+		{
+			name: "nested fields are flattened",
+			def: IntegrationDefinition{
+				ConfigFields: []ConfigField{{Key: "proxy", Type: "object", ConfigFields: []ConfigField{field("proxyHost")}}},
+			},
+			wantFields: []string{
+				"proxy config field (configFields)",
+				"proxyHost config field (configFields)",
+			},
+		},
+		{
+			name: "duplicates of the same kind keep the first definition",
+			def: IntegrationDefinition{
+				ConfigFields:   []ConfigField{field("host")},
+				ConfigSections: []ConfigSection{{DisplayName: "Advanced", ConfigFields: []ConfigField{field("host")}}},
+				AuthSections: []AuthSection{
+					{ID: "token", ConfigFields: []ConfigField{field("user"), field("apiToken")}},
+					{ID: "basic", ConfigFields: []ConfigField{field("user"), field("password")}},
+				},
+			},
+			wantFields: []string{
+				"host config field (configFields)",
+				`user auth field (authSection "token")`,
+				`apiToken auth field (authSection "token")`,
+				`password auth field (authSection "basic")`,
+			},
+		},
+		{
+			name: "same key with different kinds",
+			def: IntegrationDefinition{
+				ConfigFields: []ConfigField{field("token"), masked("secretKey")},
+				AuthSections: []AuthSection{{ID: "token", ConfigFields: []ConfigField{field("token"), field("secretKey")}}},
+			},
+			wantFields: []string{
+				"token config field (configFields)",
+				"secretKey masked config field (configFields)",
+			},
+			wantProblems: []string{
+				`key "secretKey" is defined as masked config field in configFields and as auth field in authSection "token"`,
+				`key "token" is defined as config field in configFields and as auth field in authSection "token"`,
+			},
+		},
+		{
+			name: "invalid values key",
+			def: IntegrationDefinition{
+				ConfigFields: []ConfigField{field("api-url"), field("apiUrl")},
+			},
+			wantFields: []string{
+				"api-url config field (configFields)",
+				"apiUrl config field (configFields)",
+			},
+			wantProblems: []string{`key "api-url" in configFields is not a valid values key`},
+		},
+		{
+			name: "reserved keys are skipped with a warning",
+			def: IntegrationDefinition{
+				ConfigFields: []ConfigField{field("name"), field("test"), field("host"), masked("labels")},
+				AuthSections: []AuthSection{{ID: "token", ConfigFields: []ConfigField{field("selectedAuthType"), field("apiToken")}}},
+			},
+			wantFields: []string{
+				"host config field (configFields)",
+				`apiToken auth field (authSection "token")`,
+			},
+			wantWarnings: []string{
+				`auth field "selectedAuthType" in authSection "token" is skipped because the key is reserved for a key of the Secret`,
+				`config field "name" in configFields is skipped because the key is reserved for a chart value`,
+				`config field "test" in configFields is skipped because the key is reserved for a chart value`,
+				`masked config field "labels" in configFields is skipped because the key is reserved for a secret setting`,
+			},
+		},
+		{
+			name: "chart value keys are allowed in the Secret",
+			def: IntegrationDefinition{
+				ConfigFields: []ConfigField{masked("name")},
+				AuthSections: []AuthSection{{ID: "token", ConfigFields: []ConfigField{field("secretName")}}},
+			},
+			wantFields: []string{
+				"name masked config field (configFields)",
+				`secretName auth field (authSection "token")`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := newFieldRegistry(tt.def)
+
+			var fields []string
+			for _, rf := range reg.Fields {
+				fields = append(fields, fmt.Sprintf("%s %s (%s)", rf.Field.Key, rf.Kind, rf.Source))
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("Fields = %q, want %q", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(reg.Problems, tt.wantProblems) {
+				t.Errorf("Problems = %q, want %q", reg.Problems, tt.wantProblems)
+			}
+			if !reflect.DeepEqual(reg.Warnings, tt.wantWarnings) {
+				t.Errorf("Warnings = %q, want %q", reg.Warnings, tt.wantWarnings)
+			}
+			if (reg.Err() != nil) != (len(tt.wantProblems) > 0) {
+				t.Errorf("Err() = %v, want an error only for problems", reg.Err())
+			}
+		})
+	}
+}
+
+func TestGenerateChartSkipsReservedKeys(t *testing.T) {
+	def := testDefinition()
+	def.ConfigFields = append(def.ConfigFields, ConfigField{Key: "name", DisplayName: "Name", Type: "string"})
+	def.AuthSections[0].ConfigFields = append(def.AuthSections[0].ConfigFields,
+		ConfigField{Key: "selectedAuthType", DisplayName: "Auth type", Type: "string", Mask: true})
+
+	chartDir, result := generateTestChartReport(t, def)
+
+	want := []string{
+		`auth field "selectedAuthType" in authSection "token" is skipped because the key is reserved for a key of the Secret`,
+		`config field "name" in configFields is skipped because the key is reserved for a chart value`,
+	}
+	if !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", result.Warnings, want)
+	}
+
+	values, err := os.ReadFile(filepath.Join(chartDir, "values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(values), "\nname:") {
+		t.Errorf("values.yaml has the reserved field:\n%s", values)
+	}
+	if n := strings.Count(string(values), "selectedAuthType:"); n != 1 {
+		t.Errorf("values.yaml has %d selectedAuthType keys, want 1:\n%s", n, values)
+	}
+
+	// The chart renders without values for the skipped fields
+	out := renderTestChart(t, chartDir, "organization: acme\nsecret:\n  apiToken: token\n")
+	if strings.HasPrefix(out, "Error:") {
+		t.Errorf("render failed: %s", out)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	importInstanceName string
+	importFile         string
+	importCmd          = &cobra.Command{
+		Use:   "import",
+		Short: "Write a values file that adopts an existing JupiterOne integration instance",
+		Long: `import looks up an existing integration instance by name through the JupiterOne
+GraphQL API and writes a values file for the integration's generated chart.
+
+The values file sets integrationInstanceId so that the operator manages the existing
+instance instead of creating a duplicate. Only non-secret configuration is exported;
+credentials must be supplied separately under secret: or through an existing secret.`,
+		RunE: runImport,
+	}
+)
+
+func init() {
+	importCmd.Flags().StringVarP(&integrationName, "name", "n", "", "Integration definition name, e.g. github (required)")
+	importCmd.Flags().StringVarP(&importInstanceName, "instance", "i", "", "Name of the existing integration instance (required)")
+	importCmd.Flags().StringVarP(&importFile, "file", "f", "", "Write the values to this file instead of stdout")
+	importCmd.MarkFlagRequired("name")
+	importCmd.MarkFlagRequired("instance")
+}
+
+// IntegrationInstance is an integration instance as returned by the GraphQL API
+type IntegrationInstance struct {
+	ID                            string           `json:"id"`
+	Name                          string           `json:"name"`
+	IntegrationDefinitionID       string           `json:"integrationDefinitionId"`
+	PollingInterval               string           `json:"pollingInterval"`
+	PollingIntervalCronExpression *PollingCronExpr `json:"pollingIntervalCronExpression"`
+	ResourceGroupID               string           `json:"resourceGroupId"`
+	Config                        map[string]any   `json:"config"`
+}
+
+type PollingCronExpr struct {
+	Hour      *int `json:"hour"`
+	DayOfWeek *int `json:"dayOfWeek"`
+}
+
+type IntegrationInstancesData struct {
+	IntegrationInstances struct {
+		Instances []IntegrationInstance `json:"instances"`
+		PageInfo  PageInfo              `json:"pageInfo"`
+	} `json:"integrationInstances"`
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	def, err := fetchIntegrationByName(integrationName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch integration %s: %w", integrationName, err)
+	}
+
+	instances, err := fetchIntegrationInstances(def.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch instances of %s: %w", integrationName, err)
+	}
+
+	var matches []IntegrationInstance
+	for _, instance := range instances {
+		if instance.Name == importInstanceName {
+			matches = append(matches, instance)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no %s instance named %q found", integrationName, importInstanceName)
+	case 1:
+	default:
+		var ids []string
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+		return fmt.Errorf("found %d %s instances named %q (%s); rename one of them in JupiterOne first", len(matches), integrationName, importInstanceName, strings.Join(ids, ", "))
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Found instance %s (%s)\n", matches[0].Name, matches[0].ID)
+	}
+
+	values, err := generateImportValues(*def, matches[0])
+	if err != nil {
+		return fmt.Errorf("failed to generate values: %w", err)
+	}
+
+	if importFile == "" {
+		fmt.Print(values)
+		return nil
+	}
+
+	if err := os.WriteFile(importFile, []byte(values), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", importFile, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote values for %s to %s\n", importInstanceName, importFile)
+	return nil
+}
+
+func fetchIntegrationInstances(definitionID string) ([]IntegrationInstance, error) {
+	var allInstances []IntegrationInstance
+	var cursor *string
+
+	query := `query ListIntegrationInstances($definitionId: String, $cursor: String) {
+    integrationInstances(definitionId: $definitionId, cursor: $cursor) {
+      instances {
+        id
+        name
+        integrationDefinitionId
+        pollingInterval
+        pollingIntervalCronExpression {
+          hour
+          dayOfWeek
+        }
+        resourceGroupId
+        config
+      }
+      pageInfo {
+        endCursor
+        hasNextPage
+      }
+    }
+  }`
+
+	for {
+		variables := map[string]any{
+			"definitionId": definitionID,
+		}
+		if cursor != nil {
+			variables["cursor"] = *cursor
+		}
+
+		var data IntegrationInstancesData
+		if err := executeGraphQL(query, variables, &data); err != nil {
+			return nil, err
+		}
+
+		allInstances = append(allInstances, data.IntegrationInstances.Instances...)
+
+		if !data.IntegrationInstances.PageInfo.HasNextPage {
+			break
+		}
+
+		cursor = &data.IntegrationInstances.PageInfo.EndCursor
+	}
+
+	return allInstances, nil
+}
+
+// generateImportValues renders a values file that adopts the instance. Masked config
+// fields and auth fields are never exported.
+func generateImportValues(def IntegrationDefinition, instance IntegrationInstance) (string, error) {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, value any, comment string) error {
+		valueNode, err := valueToNode(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: key, HeadComment: comment}
+		doc.Content = append(doc.Content, keyNode, valueNode)
+		return nil
+	}
+
+	if err := add("integrationInstanceId", instance.ID, "Adopt the existing JupiterOne instance instead of creating a new one"); err != nil {
+		return "", err
+	}
+	if err := add("fullnameOverride", sanitizeChartName(instance.Name), fmt.Sprintf("Kubernetes name for the instance %q", instance.Name)); err != nil {
+		return "", err
+	}
+
+	cron := instance.PollingIntervalCronExpression
+	if cron != nil && cron.Hour != nil && cron.DayOfWeek != nil {
+		if err := add("pollingInterval", nil, ""); err != nil {
+			return "", err
+		}
+		schedule := map[string]int{"hour": *cron.Hour, "dayOfWeek": *cron.DayOfWeek}
+		if err := add("pollingIntervalCron", schedule, ""); err != nil {
+			return "", err
+		}
+	} else if instance.PollingInterval != "" {
+		if err := add("pollingInterval", instance.PollingInterval, ""); err != nil {
+			return "", err
+		}
+	}
+
+	if instance.ResourceGroupID != "" {
+		if err := add("resourceGroupId", instance.ResourceGroupID, ""); err != nil {
+			return "", err
+		}
+	}
+
+	comment := "Integration configuration"
+	for _, cf := range getNonMaskedConfigFields(def) {
+		value, ok := instance.Config[cf.Key]
+		if !ok || value == nil {
+			continue
+		}
+		if err := add(cf.Key, value, comment); err != nil {
+			return "", err
+		}
+		comment = ""
+	}
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("# Values generated by chartgen import for the %s instance %q.\n", def.Title, instance.Name))
+	buf.WriteString("# Credentials are not exported: set them under secret: or set createSecret: false\n")
+	buf.WriteString("# and secretName to an existing secret.\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
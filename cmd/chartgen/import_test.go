@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func intPointer(i int) *int {
+	return &i
+}
+
+// importDefinition returns an integration with config fields of several types and a
+// masked config field, which import must not export
+func importDefinition() IntegrationDefinition {
+	def := testDefinition()
+	def.ConfigFields = []ConfigField{
+		{Key: "organization", Type: "string"},
+		{Key: "maxPages", Type: "number", Optional: true},
+		{Key: "verifySsl", Type: "boolean", Optional: true},
+		{Key: "clientSecret", Type: "string", Mask: true, Optional: true},
+	}
+	return def
+}
+
+func TestGenerateImportValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance IntegrationInstance
+	}{
+		{
+			name: "polling interval",
+			instance: IntegrationInstance{
+				ID:              "5f6d3a4e-0000-4000-8000-000000000001",
+				Name:            "GitHub (Production)",
+				PollingInterval: "ONE_DAY",
+				ResourceGroupID: "resource-group",
+				Config: map[string]any{
+					"organization": "acme",
+					"maxPages":     json.Number("10"),
+					"verifySsl":    false,
+					"clientSecret": "***masked***",
+					"unknown":      "value",
+				},
+			},
+		},
+		{
+			name: "cron schedule",
+			instance: IntegrationInstance{
+				ID:                            "5f6d3a4e-0000-4000-8000-000000000002",
+				Name:                          "nightly",
+				PollingInterval:               "DISABLED",
+				PollingIntervalCronExpression: &PollingCronExpr{Hour: intPointer(2), DayOfWeek: intPointer(0)},
+				Config:                        map[string]any{"organization": "acme", "maxPages": nil},
+			},
+		},
+		{
+			name: "no configuration",
+			instance: IntegrationInstance{
+				ID:   "5f6d3a4e-0000-4000-8000-000000000003",
+				Name: "empty",
+			},
+		},
+	}
+
+	def := importDefinition()
+	chartDir := generateTestChart(t, def)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := generateImportValues(def, tt.instance)
+			if err != nil {
+				t.Fatalf("generateImportValues() error = %v", err)
+			}
+
+			// The values adopt the instance when installed with credentials
+			values += "secret:\n  apiToken: token\n"
+			rendered := renderTestChart(t, chartDir, values, "integrationinstance.yaml")
+			assertGolden(t, values+"---\n"+rendered)
+		})
+	}
+}
+
+// serveImport serves a definition and its instances, two per page, like the
+// JupiterOne GraphQL API
+func serveImport(t *testing.T, def IntegrationDefinition, instances []IntegrationInstance) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var data any
+		switch {
+		case strings.Contains(req.Query, "findIntegrationDefinition"):
+			data = map[string]any{"findIntegrationDefinition": def}
+		case strings.Contains(req.Query, "integrationInstances"):
+			start := 0
+			if cursor, ok := req.Variables["cursor"].(string); ok {
+				start = len(cursor)
+			}
+			end := min(start+2, len(instances))
+			var page IntegrationInstancesData
+			page.IntegrationInstances.Instances = instances[start:end]
+			page.IntegrationInstances.PageInfo = PageInfo{
+				EndCursor:   strings.Repeat("x", end),
+				HasNextPage: end < len(instances),
+			}
+			data = page
+		default:
+			t.Errorf("unexpected query %q", req.Query)
+		}
+		if err := json.NewEncoder(w).Encode(map[string]any{"data": data}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("J1_GRAPHQL_ENDPOINT", server.URL)
+}
+
+func TestRunImport(t *testing.T) {
+	instances := []IntegrationInstance{
+		{ID: "id-1", Name: "production", PollingInterval: "ONE_DAY", Config: map[string]any{"organization": "acme"}},
+		{ID: "id-2", Name: "staging"},
+		{ID: "id-3", Name: "duplicate"},
+		{ID: "id-4", Name: "duplicate"},
+		{ID: "id-5", Name: "last", Config: map[string]any{"organization": "last-page"}},
+	}
+	serveImport(t, importDefinition(), instances)
+
+	savedName, savedInstance, savedFile := integrationName, importInstanceName, importFile
+	t.Cleanup(func() {
+		integrationName, importInstanceName, importFile = savedName, savedInstance, savedFile
+	})
+	integrationName = "example"
+
+	tests := []struct {
+		instance string
+		wantID   string
+		wantErr  string
+	}{
+		{instance: "production", wantID: "id-1"},
+		// Found on the last page of instances
+		{instance: "last", wantID: "id-5"},
+		{instance: "missing", wantErr: `no example instance named "missing" found`},
+		{instance: "duplicate", wantErr: `found 2 example instances named "duplicate" (id-3, id-4)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.instance, func(t *testing.T) {
+			importInstanceName = tt.instance
+			importFile = filepath.Join(t.TempDir(), "values.yaml")
+
+			err := runImport(nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runImport() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runImport() error = %v", err)
+			}
+
+			values, err := os.ReadFile(importFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "integrationInstanceId: \"" + tt.wantID + "\"\n"; !strings.Contains(string(values), want) {
+				t.Errorf("values do not contain %q:\n%s", want, values)
+			}
+		})
+	}
+}
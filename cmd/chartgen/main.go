@@ -1,21 +1,23 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 //go:embed templates/*.tmpl
@@ -24,8 +26,85 @@ var templateFS embed.FS
 const (
 	// Default GraphQL endpoint for fetching integration definitions
 	defaultGraphQLEndpoint = "https://graphql.us.jupiterone.io"
+
+	// Maximum length of a generated chart name
+	maxChartNameLength = 53
+
+	// Width that comments in generated values.yaml files are wrapped to
+	commentWidth = 80
 )
 
+// chartNamePattern matches valid chart names (lowercase alphanumerics and hyphens)
+var chartNamePattern = regexp.MustCompile("^[a-z0-9]([a-z0-9-]*[a-z0-9])?$")
+
+// pollingIntervals are the values accepted by IntegrationInstance.spec.pollingInterval.
+// The values template, the values schema and the render-time validation all use this list.
+var pollingIntervals = []string{
+	"DISABLED",
+	"THIRTY_MINUTES",
+	"ONE_HOUR",
+	"FOUR_HOURS",
+	"EIGHT_HOURS",
+	"TWELVE_HOURS",
+	"ONE_DAY",
+	"ONE_WEEK",
+}
+
+const (
+	// Polling interval of instances that set neither pollingInterval nor
+	// pollingIntervalCron (must be one of pollingIntervals)
+	defaultPollingInterval = "ONE_WEEK"
+
+	// Upper bounds of the pollingIntervalCron fields (both start at 0)
+	cronHourMax      = 23
+	cronDayOfWeekMax = 6
+)
+
+// pollingScheduleData describes the allowed polling schedule for templates
+type pollingScheduleData struct {
+	PollingIntervals       []string
+	DefaultPollingInterval string
+	CronHourMax            int
+	CronDayOfWeekMax       int
+}
+
+func newPollingScheduleData() pollingScheduleData {
+	return pollingScheduleData{
+		PollingIntervals:       pollingIntervals,
+		DefaultPollingInterval: defaultPollingInterval,
+		CronHourMax:            cronHourMax,
+		CronDayOfWeekMax:       cronDayOfWeekMax,
+	}
+}
+
+// helmignore is the .helmignore written to every generated chart
+const helmignore = `# Patterns to ignore when building Helm packages.
+# Operating system files
+.DS_Store
+
+# Version control directories
+.git/
+.gitignore
+.bzr/
+.hg/
+.hgignore
+.svn/
+
+# Backup and temporary files
+*.swp
+*.tmp
+*.bak
+*.orig
+*~
+
+# IDE and editor-related files
+.idea/
+.vscode/
+
+# Helm chart artifacts
+dist/chart/*.tgz
+`
+
 // getGraphQLEndpoint returns the GraphQL endpoint, checking for environment variable override
 func getGraphQLEndpoint() string {
 	if endpoint := os.Getenv("J1_GRAPHQL_ENDPOINT"); endpoint != "" {
@@ -40,11 +119,6 @@ type GraphQLRequest struct {
 	Variables map[string]any `json:"variables,omitempty"`
 }
 
-type GraphQLResponse struct {
-	Data   IntegrationDefinitionsData `json:"data"`
-	Errors []GraphQLError             `json:"errors,omitempty"`
-}
-
 type GraphQLError struct {
 	Message string `json:"message"`
 }
@@ -67,6 +141,9 @@ type IntegrationDefinition struct {
 	ConfigFields                []ConfigField               `json:"configFields"`
 	ConfigSections              []ConfigSection             `json:"configSections"`
 	AuthSections                []AuthSection               `json:"authSections"`
+
+	// Integration version, fetched separately by setIntegrationVersions
+	Version string `json:"-"`
 }
 
 type IntegrationPlatformFeatures struct {
@@ -86,6 +163,11 @@ type ConfigField struct {
 	Mask         bool           `json:"mask"`
 	Optional     bool           `json:"optional"`
 	ConfigFields []ConfigField  `json:"configFields"` // Nested config fields
+
+	// Presentation overrides from chartgen.yaml
+	Hidden     bool   `json:"-"`
+	Deprecated string `json:"-"`
+	Comment    string `json:"-"`
 }
 
 type ConfigOption struct {
@@ -118,6 +200,8 @@ var (
 	integrationName string
 	write           bool
 	verbose         bool
+	failFast        bool
+	maxFailures     int
 	rootCmd         = &cobra.Command{
 		Use:   "chartgen",
 		Short: "Generate Helm charts for JupiterOne integrations that support collectors",
@@ -127,18 +211,32 @@ and generates Helm charts for each integration that supports collectors.
 The generated charts create IntegrationInstance custom resources that can be
 deployed alongside the jupiterone-integration-operator.`,
 		RunE: runChartGen,
+		// main prints the returned error
+		SilenceErrors: true,
 	}
 )
 
 func init() {
-	rootCmd.Flags().StringVarP(&apiKey, "api-key", "k", "", "JupiterOne API key (required)")
-	rootCmd.Flags().StringVarP(&accountID, "account-id", "a", "", "JupiterOne account ID (required)")
+	rootCmd.PersistentFlags().StringVarP(&apiKey, "api-key", "k", "", "JupiterOne API key (required)")
+	rootCmd.PersistentFlags().StringVarP(&accountID, "account-id", "a", "", "JupiterOne account ID (required)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.Flags().StringVarP(&outputDir, "output", "o", "./charts", "Output directory for generated charts")
 	rootCmd.Flags().StringVarP(&integrationName, "name", "n", "", "Generate chart for a specific integration by name")
+	rootCmd.Flags().StringArrayVar(&includePatterns, "include", nil, "Only generate integrations matching this glob (repeatable)")
+	rootCmd.Flags().StringArrayVar(&excludePatterns, "exclude", nil, "Skip integrations matching this glob (repeatable)")
 	rootCmd.Flags().BoolVarP(&write, "write", "w", false, "Write files to disk (default is dry-run mode)")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
-	rootCmd.MarkFlagRequired("api-key")
-	rootCmd.MarkFlagRequired("account-id")
+	rootCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to the chartgen configuration file with per-integration overrides")
+	rootCmd.Flags().StringVarP(&templateOverridesDir, "templates-dir", "t", "", "Directory of templates that override or extend the built-in templates")
+	rootCmd.Flags().StringVar(&reportFormat, "report", "", "Write a run report in the given format (json or markdown)")
+	rootCmd.Flags().StringVar(&reportFile, "report-file", "", "File to write the run report to (default stdout)")
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop at the first chart failure beyond --max-failures instead of generating the remaining charts")
+	rootCmd.Flags().IntVar(&maxFailures, "max-failures", 0, "Number of chart failures tolerated before the run exits with a non-zero status")
+	rootCmd.Flags().BoolVar(&rollbackOnFailure, "rollback", false, "Restore every chart written in this run if any chart fails to generate")
+	rootCmd.Flags().BoolVar(&fetchVersions, "fetch-versions", false, "Use the integration versions from the API as the appVersion of the charts")
+	rootCmd.MarkPersistentFlagRequired("api-key")
+	rootCmd.MarkPersistentFlagRequired("account-id")
+
+	rootCmd.AddCommand(importCmd)
 }
 
 func main() {
@@ -149,28 +247,42 @@ func main() {
 }
 
 func runChartGen(cmd *cobra.Command, args []string) error {
-	// If a specific integration name is provided, fetch and generate only that one
-	if integrationName != "" {
-		def, err := fetchIntegrationByName(integrationName)
-		if err != nil {
-			return fmt.Errorf("failed to fetch integration %s: %w", integrationName, err)
-		}
+	// Errors from here on are not usage errors
+	cmd.SilenceUsage = true
 
-		if def == nil {
-			return fmt.Errorf("integration %s not found", integrationName)
-		}
+	if maxFailures < 0 {
+		return fmt.Errorf("--max-failures must not be negative")
+	}
 
-		err, changed := generateChart(*def)
-		if err != nil {
-			return fmt.Errorf("failed to generate chart for %s: %w", integrationName, err)
-		}
+	conf, err := loadConfig(configPath, cmd.Flags().Changed("config"))
+	if err != nil {
+		return err
+	}
+	chartgenConf = conf
 
-		if changed {
-			fmt.Printf("Successfully generated chart for %s\n", integrationName)
-		} else {
-			fmt.Printf("No changes for %s\n", integrationName)
+	if err := validateReportFormat(); err != nil {
+		return err
+	}
+	redirectProgress()
+
+	if err := reportTemplateOverrides(); err != nil {
+		return err
+	}
+
+	if write {
+		recoverWorkDirs()
+	}
+	// Drop the backups kept for --rollback once the run is over
+	defer commitChartWrites()
+	defer func() {
+		if err := writeRunReport(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		return nil
+	}()
+
+	selector, err := newIntegrationSelector()
+	if err != nil {
+		return err
 	}
 
 	// Fetch all integration definitions
@@ -180,36 +292,109 @@ func runChartGen(cmd *cobra.Command, args []string) error {
 	}
 
 	if verbose {
-		fmt.Printf("Fetched %d total integration definitions\n", len(definitions))
+		fmt.Fprintf(progress, "Fetched %d total integration definitions\n", len(definitions))
 	}
 
-	// Filter for collector-supported integrations
+	if integrationName != "" && !definitionExists(definitions, integrationName) {
+		return fmt.Errorf("integration %s not found", integrationName)
+	}
+
+	// Select the integrations to generate
 	collectorSupported := filterCollectorSupported(definitions)
+	selected := selectIntegrations(definitions, selector)
 
 	if verbose {
-		fmt.Printf("Found %d integrations that support collectors\n", len(collectorSupported))
+		fmt.Fprintf(progress, "Found %d integrations that support collectors, %d selected\n", len(collectorSupported), len(selected))
+	}
+
+	// Only the selected integrations are checked, so excluding one of two integrations
+	// with the same chart name resolves the collision
+	if err := validateChartNames(selected); err != nil {
+		for _, def := range selected {
+			addChartReport(ChartReport{Integration: def.Name, Status: statusFailed, Reason: "chart names are invalid, see the error output"})
+		}
+		return err
 	}
 
-	if len(collectorSupported) == 0 {
-		fmt.Println("No integrations found that support collectors")
+	if len(selected) == 0 {
+		fmt.Fprintln(progress, "No integrations selected")
 		return nil
 	}
 
+	setIntegrationVersions(selected)
+
 	// Generate charts
 	updated := 0
-	for _, def := range collectorSupported {
-		err, changed := generateChart(def)
+	var failures []chartFailure
+	for i, def := range selected {
+		result := ChartReport{Integration: def.Name}
+		err, changed := generateChart(def, &result)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to generate chart for %s: %v\n", def.Name, err)
+			result.Status = statusFailed
+			result.Reason = err.Error()
+			addChartReport(result)
+			failures = append(failures, chartFailure{name: def.Name, err: err})
+
+			if failFast && len(failures) > maxFailures {
+				for _, rest := range selected[i+1:] {
+					addChartReport(ChartReport{Integration: rest.Name, Status: statusSkipped, Reason: "not attempted, stopped after failures (--fail-fast)"})
+				}
+				fmt.Fprintf(os.Stderr, "Stopping after %d failed chart(s) (--fail-fast)\n", len(failures))
+				break
+			}
 			continue
 		}
+		addChartReport(result)
 		if changed {
 			updated++
 		}
 	}
 
-	fmt.Printf("Updated %d of %d charts\n", updated, len(collectorSupported))
-	return nil
+	if len(failures) > 0 && rollbackOnFailure {
+		fmt.Fprintf(os.Stderr, "%d charts failed, rolling back %d updated charts\n", len(failures), updated)
+		if err := rollbackChartWrites(); err != nil {
+			return err
+		}
+		markRolledBack()
+		updated = 0
+	}
+
+	fmt.Fprintf(progress, "Updated %d of %d charts\n", updated, len(selected))
+
+	// Regenerate the umbrella chart so it pins the versions just written
+	if err := updateStackChart(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to generate chart for %s: %v\n", stackChartName, err)
+		failures = append(failures, chartFailure{name: stackChartName, err: err})
+	}
+
+	return failureSummary(failures)
+}
+
+// chartFailure is a chart that failed to generate in a bulk run
+type chartFailure struct {
+	name string
+	err  error
+}
+
+// failureSummary returns an error listing every failed chart when there are more
+// failures than --max-failures allows, and nil otherwise
+func failureSummary(failures []chartFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(failures))
+	for i, f := range failures {
+		lines[i] = fmt.Sprintf("%s: %s", f.name, strings.Join(strings.Fields(f.err.Error()), " "))
+	}
+	summary := fmt.Sprintf("failed to generate %d chart(s):\n  %s", len(failures), strings.Join(lines, "\n  "))
+
+	if len(failures) <= maxFailures {
+		fmt.Fprintf(os.Stderr, "%s\n(tolerated by --max-failures=%d)\n", summary, maxFailures)
+		return nil
+	}
+	return errors.New(summary)
 }
 
 func fetchAllIntegrationDefinitions() ([]IntegrationDefinition, error) {
@@ -387,57 +572,18 @@ func fetchAllIntegrationDefinitions() ([]IntegrationDefinition, error) {
 			variables["cursor"] = *cursor
 		}
 
-		reqBody := GraphQLRequest{
-			Query:     query,
-			Variables: variables,
-		}
-
-		jsonBody, err := json.Marshal(reqBody)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
-		}
-
-		req, err := http.NewRequest("POST", getGraphQLEndpoint(), bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-		req.Header.Set("JupiterOne-Account", accountID)
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		var data IntegrationDefinitionsData
+		if err := executeGraphQL(query, variables, &data); err != nil {
+			return nil, err
 		}
 
-		var graphqlResp GraphQLResponse
-		if err := json.Unmarshal(body, &graphqlResp); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-		}
+		allDefinitions = append(allDefinitions, data.IntegrationDefinitions.Definitions...)
 
-		if len(graphqlResp.Errors) > 0 {
-			return nil, fmt.Errorf("GraphQL errors: %v", graphqlResp.Errors)
-		}
-
-		allDefinitions = append(allDefinitions, graphqlResp.Data.IntegrationDefinitions.Definitions...)
-
-		if !graphqlResp.Data.IntegrationDefinitions.PageInfo.HasNextPage {
+		if !data.IntegrationDefinitions.PageInfo.HasNextPage {
 			break
 		}
 
-		cursor = &graphqlResp.Data.IntegrationDefinitions.PageInfo.EndCursor
+		cursor = &data.IntegrationDefinitions.PageInfo.EndCursor
 	}
 
 	return allDefinitions, nil
@@ -606,6 +752,22 @@ func fetchIntegrationByName(name string) (*IntegrationDefinition, error) {
 		"integrationType": name,
 	}
 
+	var data struct {
+		FindIntegrationDefinition *IntegrationDefinition `json:"findIntegrationDefinition"`
+	}
+	if err := executeGraphQL(query, variables, &data); err != nil {
+		return nil, err
+	}
+
+	if data.FindIntegrationDefinition == nil {
+		return nil, fmt.Errorf("integration %q not found", name)
+	}
+
+	return data.FindIntegrationDefinition, nil
+}
+
+// executeGraphQL sends a query to the JupiterOne GraphQL API and decodes the data field into data
+func executeGraphQL(query string, variables map[string]any, data any) error {
 	reqBody := GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -613,12 +775,12 @@ func fetchIntegrationByName(name string) (*IntegrationDefinition, error) {
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", getGraphQLEndpoint(), bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -628,39 +790,51 @@ func fetchIntegrationByName(name string) (*IntegrationDefinition, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
 	var graphqlResp struct {
-		Data struct {
-			FindIntegrationDefinition *IntegrationDefinition `json:"findIntegrationDefinition"`
-		} `json:"data"`
-		Errors []GraphQLError `json:"errors,omitempty"`
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors,omitempty"`
 	}
 
 	if err := json.Unmarshal(body, &graphqlResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if len(graphqlResp.Errors) > 0 {
-		return nil, fmt.Errorf("GraphQL errors: %v", graphqlResp.Errors)
+		return fmt.Errorf("GraphQL errors: %v", graphqlResp.Errors)
 	}
 
-	if graphqlResp.Data.FindIntegrationDefinition == nil {
-		return nil, fmt.Errorf("integration %q not found", name)
+	// Keep numbers as json.Number so that values such as default values keep their
+	// exact precision instead of becoming float64 (1000000 would print as 1e+06)
+	decoder := json.NewDecoder(bytes.NewReader(graphqlResp.Data))
+	decoder.UseNumber()
+	if err := decoder.Decode(data); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return graphqlResp.Data.FindIntegrationDefinition, nil
+	return nil
+}
+
+// definitionExists returns true if a definition has the given name or integration type
+func definitionExists(definitions []IntegrationDefinition, name string) bool {
+	for _, def := range definitions {
+		if def.Name == name || def.IntegrationType == name {
+			return true
+		}
+	}
+	return false
 }
 
 func filterCollectorSupported(definitions []IntegrationDefinition) []IntegrationDefinition {
@@ -706,113 +880,32 @@ func flattenConfigFields(fields []ConfigField, seen map[string]bool) []ConfigFie
 
 // getNonMaskedConfigFields returns all non-masked config fields (from configFields and configSections)
 func getNonMaskedConfigFields(def IntegrationDefinition) []ConfigField {
-	seen := make(map[string]bool)
-	var fields []ConfigField
-
-	// Flatten all configFields (including nested)
-	allConfigFields := flattenConfigFields(def.ConfigFields, make(map[string]bool))
-
-	// Add non-masked configFields
-	for _, cf := range allConfigFields {
-		if !seen[cf.Key] && !cf.Mask {
-			seen[cf.Key] = true
-			fields = append(fields, cf)
-		}
-	}
-
-	// Flatten and add non-masked fields from configSections
-	for _, cs := range def.ConfigSections {
-		sectionFields := flattenConfigFields(cs.ConfigFields, make(map[string]bool))
-		for _, cf := range sectionFields {
-			if !seen[cf.Key] && !cf.Mask {
-				seen[cf.Key] = true
-				fields = append(fields, cf)
-			}
-		}
-	}
-
-	return fields
+	return newFieldRegistry(def).fieldsOf(fieldConfig)
 }
 
 // getMaskedConfigFields returns all masked config fields (from configFields and configSections)
 func getMaskedConfigFields(def IntegrationDefinition) []ConfigField {
-	seen := make(map[string]bool)
-	var fields []ConfigField
-
-	// Flatten all configFields (including nested)
-	allConfigFields := flattenConfigFields(def.ConfigFields, make(map[string]bool))
-
-	// Add masked configFields
-	for _, cf := range allConfigFields {
-		if !seen[cf.Key] && cf.Mask {
-			seen[cf.Key] = true
-			fields = append(fields, cf)
-		}
-	}
-
-	// Flatten and add masked fields from configSections
-	for _, cs := range def.ConfigSections {
-		sectionFields := flattenConfigFields(cs.ConfigFields, make(map[string]bool))
-		for _, cf := range sectionFields {
-			if !seen[cf.Key] && cf.Mask {
-				seen[cf.Key] = true
-				fields = append(fields, cf)
-			}
-		}
-	}
-
-	return fields
+	return newFieldRegistry(def).fieldsOf(fieldMasked)
 }
 
 // getAllConfigFields returns all config fields (from configFields and configSections)
 func getAllConfigFields(def IntegrationDefinition) []ConfigField {
-	seen := make(map[string]bool)
-	var fields []ConfigField
-
-	// Flatten and add all configFields (including nested)
-	allConfigFields := flattenConfigFields(def.ConfigFields, make(map[string]bool))
-	for _, cf := range allConfigFields {
-		if !seen[cf.Key] {
-			seen[cf.Key] = true
-			fields = append(fields, cf)
-		}
-	}
-
-	// Flatten and add fields from configSections
-	for _, cs := range def.ConfigSections {
-		sectionFields := flattenConfigFields(cs.ConfigFields, make(map[string]bool))
-		for _, cf := range sectionFields {
-			if !seen[cf.Key] {
-				seen[cf.Key] = true
-				fields = append(fields, cf)
-			}
-		}
-	}
-
-	return fields
+	return newFieldRegistry(def).fieldsOf(fieldConfig, fieldMasked)
 }
 
 // getAllAuthFields returns all sensitive auth fields (from authSections), deduplicated
 func getAllAuthFields(def IntegrationDefinition) []ConfigField {
-	seen := make(map[string]bool)
-	var fields []ConfigField
-
-	for _, as := range def.AuthSections {
-		// Flatten auth section fields (including nested)
-		sectionFields := flattenConfigFields(as.ConfigFields, make(map[string]bool))
-		for _, cf := range sectionFields {
-			if !seen[cf.Key] {
-				seen[cf.Key] = true
-				fields = append(fields, cf)
-			}
-		}
-	}
-
-	return fields
+	return newFieldRegistry(def).fieldsOf(fieldAuth)
 }
 
-// getFlattenedAuthSections returns auth sections with flattened configFields
+// getFlattenedAuthSections returns auth sections with flattened configFields. Fields
+// left out of the field registry, such as those with reserved keys, are dropped.
 func getFlattenedAuthSections(def IntegrationDefinition) []AuthSection {
+	registered := make(map[string]bool)
+	for _, cf := range getAllAuthFields(def) {
+		registered[cf.Key] = true
+	}
+
 	var result []AuthSection
 	for _, as := range def.AuthSections {
 		flattened := AuthSection{
@@ -820,7 +913,11 @@ func getFlattenedAuthSections(def IntegrationDefinition) []AuthSection {
 			DisplayName:          as.DisplayName,
 			Description:          as.Description,
 			VerificationDisabled: as.VerificationDisabled,
-			ConfigFields:         flattenConfigFields(as.ConfigFields, make(map[string]bool)),
+		}
+		for _, cf := range flattenConfigFields(as.ConfigFields, make(map[string]bool)) {
+			if registered[cf.Key] {
+				flattened.ConfigFields = append(flattened.ConfigFields, cf)
+			}
 		}
 		result = append(result, flattened)
 	}
@@ -829,14 +926,69 @@ func getFlattenedAuthSections(def IntegrationDefinition) []AuthSection {
 
 // hasAuthFields returns true if the integration has any auth fields
 func hasAuthFields(def IntegrationDefinition) bool {
-	for _, as := range def.AuthSections {
-		if len(as.ConfigFields) > 0 {
+	return len(getAllAuthFields(def)) > 0
+}
+
+// Required reports whether a value must be supplied for the field. Hidden fields are
+// not documented in values.yaml, so they are never required.
+func (cf ConfigField) Required() bool {
+	return !cf.Optional && !cf.Hidden
+}
+
+// hasRequiredFields returns true if any of the fields is required
+func hasRequiredFields(fields []ConfigField) bool {
+	for _, cf := range fields {
+		if cf.Required() {
 			return true
 		}
 	}
 	return false
 }
 
+// requiredInput is a value that must be set to install a chart
+type requiredInput struct {
+	Key string
+	// AuthType is the auth section the value is required for, if any
+	AuthType string
+}
+
+func (r requiredInput) String() string {
+	if r.AuthType == "" {
+		return r.Key
+	}
+	return fmt.Sprintf("%s (when selectedAuthType is %q)", r.Key, r.AuthType)
+}
+
+// getRequiredInputs lists the values that must be set to install the chart, as they
+// are checked by the chart's templates. Auth fields are only required when their auth
+// section is selected.
+func getRequiredInputs(def IntegrationDefinition) []requiredInput {
+	var inputs []requiredInput
+	for _, cf := range getNonMaskedConfigFields(def) {
+		if cf.Required() {
+			inputs = append(inputs, requiredInput{Key: cf.Key})
+		}
+	}
+	for _, cf := range getMaskedConfigFields(def) {
+		if cf.Required() {
+			inputs = append(inputs, requiredInput{Key: "secret." + cf.Key})
+		}
+	}
+
+	sections := getFlattenedAuthSections(def)
+	if len(sections) > 1 {
+		inputs = append(inputs, requiredInput{Key: "secret.selectedAuthType"})
+	}
+	for _, as := range sections {
+		for _, cf := range as.ConfigFields {
+			if cf.Required() {
+				inputs = append(inputs, requiredInput{Key: "secret." + cf.Key, AuthType: as.ID})
+			}
+		}
+	}
+	return inputs
+}
+
 // hasSecretFields returns true if there are any masked config fields or auth fields
 func hasSecretFields(def IntegrationDefinition) bool {
 	return len(getMaskedConfigFields(def)) > 0 || hasAuthFields(def)
@@ -884,45 +1036,59 @@ func removeVersionLine(content string) string {
 	return strings.Join(lines, "\n")
 }
 
-// generateChart generates a Helm chart for the given integration definition.
+// generateChart generates a Helm chart for the given integration definition and fills
+// in result with the chart name, versions and field changes.
 // Returns (error, changed) where changed indicates if files were written.
-func generateChart(def IntegrationDefinition) (error, bool) {
-	chartName := def.Name
+func generateChart(def IntegrationDefinition, result *ChartReport) (error, bool) {
+	def = applyFieldOverrides(def)
+	chartName := getChartName(def)
+	result.Chart = chartName
 
-	// Validate chart name (must be valid Kubernetes name)
-	chartName = sanitizeChartName(chartName)
+	reg := newFieldRegistry(def)
+	if err := reg.Err(); err != nil {
+		return err, false
+	}
+
+	for _, warning := range append(reg.Warnings, unknownFormatWarnings(def)...) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", def.Name, warning)
+		result.Warnings = append(result.Warnings, warning)
+	}
 
 	configFields := getAllConfigFields(def)
 	authFields := getAllAuthFields(def)
 
 	if verbose {
-		fmt.Printf("Generating chart: %s (from %s)\n", chartName, def.Name)
+		fmt.Fprintf(progress, "Generating chart: %s (from %s)\n", chartName, def.Name)
 	}
 
 	if !write {
-		fmt.Printf("[dry-run] Would generate chart: %s\n", chartName)
-		fmt.Printf("  Title: %s\n", def.Title)
-		fmt.Printf("  Config fields: %d\n", len(configFields))
+		fmt.Fprintf(progress, "[dry-run] Would generate chart: %s\n", chartName)
+		fmt.Fprintf(progress, "  Title: %s\n", def.Title)
+		fmt.Fprintf(progress, "  App version: %s\n", getAppVersion(def))
+		fmt.Fprintf(progress, "  Config fields: %d\n", len(configFields))
 		for _, cf := range configFields {
 			optionalStr := ""
 			if cf.Optional {
 				optionalStr = " (optional)"
 			}
-			fmt.Printf("    - %s (%s)%s\n", cf.Key, cf.Type, optionalStr)
+			fmt.Fprintf(progress, "    - %s (%s)%s\n", cf.Key, cf.Type, optionalStr)
 		}
-		fmt.Printf("  Auth fields (secret): %d\n", len(authFields))
+		fmt.Fprintf(progress, "  Auth fields (secret): %d\n", len(authFields))
 		for _, cf := range authFields {
 			optionalStr := ""
 			if cf.Optional {
 				optionalStr = " (optional)"
 			}
-			fmt.Printf("    - %s (%s)%s\n", cf.Key, cf.Type, optionalStr)
+			fmt.Fprintf(progress, "    - %s (%s)%s\n", cf.Key, cf.Type, optionalStr)
+		}
+		requiredInputs := getRequiredInputs(def)
+		fmt.Fprintf(progress, "  Required inputs: %d\n", len(requiredInputs))
+		for _, input := range requiredInputs {
+			fmt.Fprintf(progress, "    - %s\n", input)
 		}
-		return nil, false
 	}
 
 	chartDir := filepath.Join(outputDir, chartName)
-	templatesDir := filepath.Join(chartDir, "templates")
 
 	// Get current version (will be used for initial generation to compare)
 	currentVersion := getCurrentChartVersion(chartName)
@@ -942,36 +1108,34 @@ func generateChart(def IntegrationDefinition) (error, bool) {
 	if err != nil {
 		return fmt.Errorf("failed to generate values.yaml: %w", err), false
 	}
+	if err := validateValuesYaml(valuesYaml); err != nil {
+		return fmt.Errorf("generated values.yaml is invalid: %w", err), false
+	}
 	files["values.yaml"] = valuesYaml
 
-	// Generate .helmignore
-	helmignore := `# Patterns to ignore when building Helm packages.
-# Operating system files
-.DS_Store
-
-# Version control directories
-.git/
-.gitignore
-.bzr/
-.hg/
-.hgignore
-.svn/
+	// Generate values.schema.json
+	valuesSchema, err := generateValuesSchema(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate values.schema.json: %w", err), false
+	}
+	files["values.schema.json"] = valuesSchema
 
-# Backup and temporary files
-*.swp
-*.tmp
-*.bak
-*.orig
-*~
+	// Generate .helmignore
+	files[".helmignore"] = helmignore
 
-# IDE and editor-related files
-.idea/
-.vscode/
+	// Generate README.md with the values tables
+	readme, err := generateReadme(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate README.md: %w", err), false
+	}
+	files["README.md"] = readme
 
-# Helm chart artifacts
-dist/chart/*.tgz
-`
-	files[".helmignore"] = helmignore
+	// Generate _helpers.tpl with shared names and labels
+	helpersTpl, err := generateHelpersTpl(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate _helpers.tpl: %w", err), false
+	}
+	files["templates/_helpers.tpl"] = helpersTpl
 
 	// Generate integrationinstance.yaml template
 	instanceYaml, err := generateIntegrationInstanceYaml(def)
@@ -980,25 +1144,70 @@ dist/chart/*.tgz
 	}
 	files["templates/integrationinstance.yaml"] = instanceYaml
 
-	// Generate secret.yaml template if there are secret fields
+	// Generate runner.yaml template for the optional inline IntegrationRunner
+	runnerYaml, err := generateRunnerYaml(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate runner.yaml: %w", err), false
+	}
+	files["templates/runner.yaml"] = runnerYaml
+
+	// Generate NOTES.txt with the installed instances and runner warnings
+	notesTxt, err := generateNotesTxt(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate NOTES.txt: %w", err), false
+	}
+	files["templates/NOTES.txt"] = notesTxt
+
+	// Generate the helm test hook that waits for the instances to be Ready
+	testYaml, err := generateTestYaml(def)
+	if err != nil {
+		return fmt.Errorf("failed to generate tests/integrationinstance-test.yaml: %w", err), false
+	}
+	files["templates/tests/integrationinstance-test.yaml"] = testYaml
+
+	// Generate the templates of every secret backend if there are secret fields
 	if hasSecretFields(def) {
-		secretYaml, err := generateSecretYaml(def)
+		secretFiles, err := generateSecretFiles(def)
 		if err != nil {
-			return fmt.Errorf("failed to generate secret.yaml: %w", err), false
+			return err, false
+		}
+		for relPath, content := range secretFiles {
+			files[relPath] = content
 		}
-		files["templates/secret.yaml"] = secretYaml
+	}
+
+	// Render extra templates from --templates-dir
+	extraFiles, err := generateExtraTemplates(def)
+	if err != nil {
+		return err, false
+	}
+	for relPath, content := range extraFiles {
+		files[relPath] = content
 	}
 
 	// Check if any content has changed (excluding version line in Chart.yaml)
 	if !chartContentChanged(chartDir, files) {
 		if verbose {
-			fmt.Printf("  No changes detected, skipping %s\n", chartName)
+			fmt.Fprintf(progress, "  No changes detected, skipping %s\n", chartName)
 		}
+		result.Status = statusUnchanged
+		result.OldVersion = currentVersion
+		result.NewVersion = currentVersion
 		return nil, false
 	}
 
 	// Content has changed - bump version and regenerate Chart.yaml
 	newVersion := bumpPatchVersion(currentVersion)
+	result.Status = statusGenerated
+	if !write {
+		result.Status = statusWouldGenerate
+	}
+	result.NewVersion = newVersion
+	if _, err := os.Stat(chartDir); err == nil {
+		result.OldVersion = currentVersion
+	}
+	result.FieldChanges = diffChartFields(chartDir, files)
+
 	chartYaml, err = generateChartYaml(def, newVersion)
 	if err != nil {
 		return fmt.Errorf("failed to generate Chart.yaml: %w", err), false
@@ -1006,19 +1215,19 @@ dist/chart/*.tgz
 	files["Chart.yaml"] = chartYaml
 
 	if verbose {
-		fmt.Printf("  Changes detected, bumping version %s -> %s\n", currentVersion, newVersion)
+		fmt.Fprintf(progress, "  Changes detected, bumping version %s -> %s\n", currentVersion, newVersion)
+		if current, next := readChartYamlField(chartName, "appVersion"), getAppVersion(def); current != "" && current != next {
+			fmt.Fprintf(progress, "  appVersion changed %s -> %s\n", current, next)
+		}
 	}
 
-	// Create directories and write all files
-	if err := os.MkdirAll(templatesDir, 0755); err != nil {
-		return fmt.Errorf("failed to create chart directory: %w", err), false
+	if !write {
+		return nil, false
 	}
 
-	for relPath, content := range files {
-		fullPath := filepath.Join(chartDir, relPath)
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write %s: %w", relPath, err), false
-		}
+	// Stage the chart and swap it in place of the existing one
+	if err := writeChart(chartDir, files); err != nil {
+		return err, false
 	}
 
 	return nil, true
@@ -1040,39 +1249,97 @@ func sanitizeChartName(name string) string {
 	return name
 }
 
-func loadTemplate(name string) (string, error) {
-	content, err := templateFS.ReadFile("templates/" + name)
+// getChartName returns the chart name for an integration: the chartName override from
+// the config if set, otherwise the sanitized integration name.
+func getChartName(def IntegrationDefinition) string {
+	if name := getOverrides(def).ChartName; name != "" {
+		return name
+	}
+	return sanitizeChartName(def.Name)
+}
+
+// getHandWrittenChartNames returns the charts in the output directory that chartgen
+// did not generate
+func getHandWrittenChartNames() []string {
+	entries, err := os.ReadDir(outputDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to read template %s: %w", name, err)
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || isWorkDir(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(outputDir, entry.Name(), "Chart.yaml"))
+		if err == nil && !strings.HasPrefix(string(content), generatedHeader) {
+			names = append(names, entry.Name())
+		}
 	}
-	return string(content), nil
+	return names
 }
 
-// getCurrentChartVersion reads the existing Chart.yaml (if it exists) and returns the current version.
-// If the chart doesn't exist or version can't be parsed, returns "1.0.0".
-func getCurrentChartVersion(chartName string) string {
-	chartPath := filepath.Join(outputDir, chartName, "Chart.yaml")
+// validateChartNames checks the chart names of every definition that will be generated
+// before anything is written. Names must be valid, at most maxChartNameLength characters
+// and unique across the set and the charts chartgen does not generate itself, including
+// the hand-written charts in the output directory.
+func validateChartNames(definitions []IntegrationDefinition) error {
+	sources := map[string][]string{
+		stackChartName:    {stackChartName},
+		operatorChartName: {operatorChartName},
+		runnerChartName:   {runnerChartName},
+	}
+	for _, name := range getHandWrittenChartNames() {
+		sources[name] = []string{name}
+	}
 
-	file, err := os.Open(chartPath)
-	if err != nil {
-		// Chart doesn't exist yet, start at 1.0.0
-		return "1.0.0"
+	var problems []string
+	for _, def := range definitions {
+		name := getChartName(def)
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("%s: chart name is empty after sanitizing", def.Name))
+			continue
+		case len(name) > maxChartNameLength:
+			problems = append(problems, fmt.Sprintf("%s: chart name %q is %d characters long (max %d)", def.Name, name, len(name), maxChartNameLength))
+		case !chartNamePattern.MatchString(name):
+			problems = append(problems, fmt.Sprintf("%s: chart name %q must consist of lowercase alphanumerics and hyphens", def.Name, name))
+		}
+		sources[name] = append(sources[name], def.Name)
 	}
-	defer file.Close()
-
-	// Parse the Chart.yaml to find the version line
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "version:") {
-			versionStr := strings.TrimSpace(strings.TrimPrefix(line, "version:"))
-			// Remove quotes if present
-			versionStr = strings.Trim(versionStr, "\"'")
-			return versionStr
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(sources[name]) > 1 {
+			problems = append(problems, fmt.Sprintf("chart name %q is used by %s", name, strings.Join(quoteAll(sources[name]), " and ")))
 		}
 	}
 
-	// No version found, start at 1.0.0
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid chart names (set integrations.<name>.chartName in %s to resolve):\n  %s", configPath, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return quoted
+}
+
+// getCurrentChartVersion reads the existing Chart.yaml (if it exists) and returns the current version.
+// If the chart doesn't exist or has no version, returns "1.0.0".
+func getCurrentChartVersion(chartName string) string {
+	if version := readChartYamlField(chartName, "version"); version != "" {
+		return version
+	}
 	return "1.0.0"
 }
 
@@ -1097,16 +1364,22 @@ func generateChartYaml(def IntegrationDefinition, version string) (string, error
 		return "", err
 	}
 
-	chartName := sanitizeChartName(def.Name)
+	chartName := getChartName(def)
 
 	data := struct {
-		Name    string
-		Title   string
-		Version string
+		Name        string
+		Title       string
+		Version     string
+		AppVersion  string
+		Annotations map[string]string
+		Metadata    ChartMetadata
 	}{
-		Name:    chartName,
-		Title:   def.Title,
-		Version: version,
+		Name:        chartName,
+		Title:       def.Title,
+		Version:     version,
+		AppVersion:  getAppVersion(def),
+		Annotations: getChartAnnotations(def),
+		Metadata:    getOverrides(def).Chart,
 	}
 
 	var buf bytes.Buffer
@@ -1124,7 +1397,10 @@ func generateValuesYaml(def IntegrationDefinition) (string, error) {
 	}
 
 	funcMap := template.FuncMap{
-		"formatDefaultValue": formatDefaultValue,
+		"field":     formatField,
+		"comment":   commentLines,
+		"options":   describeOptions,
+		"multiline": describeMultiline,
 	}
 
 	tmpl, err := template.New("values").Funcs(funcMap).Parse(tmplContent)
@@ -1134,12 +1410,16 @@ func generateValuesYaml(def IntegrationDefinition) (string, error) {
 
 	data := struct {
 		IntegrationDefinitionName string
+		ValuesComment             string
+		Schedule                  pollingScheduleData
 		ConfigFields              []ConfigField
 		MaskedConfigFields        []ConfigField
 		AuthSections              []AuthSection
 		HasSecretFields           bool
 	}{
 		IntegrationDefinitionName: def.Name,
+		ValuesComment:             getOverrides(def).ValuesComment,
+		Schedule:                  newPollingScheduleData(),
 		ConfigFields:              getNonMaskedConfigFields(def),
 		MaskedConfigFields:        getMaskedConfigFields(def),
 		AuthSections:              getFlattenedAuthSections(def),
@@ -1154,25 +1434,18 @@ func generateValuesYaml(def IntegrationDefinition) (string, error) {
 	return buf.String(), nil
 }
 
-func generateSecretYaml(def IntegrationDefinition) (string, error) {
-	tmplContent, err := loadTemplate("secret.yaml.tmpl")
+// renderTemplate loads the named template and executes it with data
+func renderTemplate(name string, data any) (string, error) {
+	tmplContent, err := loadTemplate(name)
 	if err != nil {
 		return "", err
 	}
 
-	tmpl, err := template.New("secret").Parse(tmplContent)
+	tmpl, err := template.New(name).Parse(tmplContent)
 	if err != nil {
 		return "", err
 	}
 
-	data := struct {
-		MaskedConfigFields []ConfigField
-		AuthFields         []ConfigField
-	}{
-		MaskedConfigFields: getMaskedConfigFields(def),
-		AuthFields:         getAllAuthFields(def),
-	}
-
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", err
@@ -1181,27 +1454,195 @@ func generateSecretYaml(def IntegrationDefinition) (string, error) {
 	return buf.String(), nil
 }
 
-func formatDefaultValue(val any) string {
-	if val == nil {
-		return ""
+// chartTemplateData is the data passed to templates that only need the chart identity
+type chartTemplateData struct {
+	ChartName string
+	Title     string
+	Schedule  pollingScheduleData
+}
+
+func newChartTemplateData(def IntegrationDefinition) chartTemplateData {
+	return chartTemplateData{
+		ChartName: getChartName(def),
+		Title:     def.Title,
+		Schedule:  newPollingScheduleData(),
 	}
-	switch v := val.(type) {
+}
+
+func generateHelpersTpl(def IntegrationDefinition) (string, error) {
+	return renderTemplate("_helpers.tpl.tmpl", newChartTemplateData(def))
+}
+
+func generateRunnerYaml(def IntegrationDefinition) (string, error) {
+	return renderTemplate("runner.yaml.tmpl", newChartTemplateData(def))
+}
+
+func generateNotesTxt(def IntegrationDefinition) (string, error) {
+	return renderTemplate("NOTES.txt.tmpl", newChartTemplateData(def))
+}
+
+func generateTestYaml(def IntegrationDefinition) (string, error) {
+	return renderTemplate("integrationinstance-test.yaml.tmpl", newChartTemplateData(def))
+}
+
+// validateValuesYaml checks that a generated values.yaml parses as a YAML mapping
+func validateValuesYaml(content string) error {
+	var values map[string]any
+	if err := yaml.Unmarshal([]byte(content), &values); err != nil {
+		return err
+	}
+	return nil
+}
+
+// commentLines turns text into YAML comment lines at the given indentation. Line
+// endings are normalized, runs of blank lines are collapsed and long lines are
+// word-wrapped to commentWidth, keeping the leading indentation of each line, so that
+// every line of the result is a comment. The result has no trailing newline.
+func commentLines(indent string, text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\t", "  ")
+	text = strings.TrimRight(strings.TrimLeft(text, "\n"), " \n")
+
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			if !blank {
+				lines = append(lines, indent+"#")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+
+		prefix := indent + "# " + line[:len(line)-len(strings.TrimLeft(line, " "))]
+		current := prefix + words[0]
+		for _, word := range words[1:] {
+			if len(current)+1+len(word) > commentWidth {
+				lines = append(lines, current)
+				current = prefix + word
+				continue
+			}
+			current += " " + word
+		}
+		lines = append(lines, current)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatField renders a config field as a values.yaml entry. A required field without a
+// default is written with an empty placeholder marked as required, so that the chart's
+// required checks fail until a value is supplied. Plain strings use "" and other fields
+// null, which passes the format checks of values.schema.json. Multi-select fields
+// without a default are written as an empty list.
+func formatField(indent string, commented bool, cf ConfigField) (string, error) {
+	if cf.Required() && cf.DefaultValue == nil {
+		placeholder := "null"
+		if (cf.Type == "" || cf.Type == "string") && normalizeFormat(cf.Format) == "" {
+			placeholder = `""`
+		}
+		return fmt.Sprintf("%s%s: %s  # required", commentPrefix(indent, commented), cf.Key, placeholder), nil
+	}
+	if cf.Multiselect() && cf.DefaultValue == nil {
+		return formatFieldValue(indent, commented, cf.Key, []any{})
+	}
+	return formatFieldValue(indent, commented, cf.Key, cf.DefaultValue)
+}
+
+// formatFieldValue renders a field and its default value as a values.yaml entry at the
+// given indentation. Strings are double-quoted (multi-line strings use a literal block),
+// numbers keep the precision they had in the API response, lists and maps are written
+// in block style, and falsy defaults such as false, 0 and [] are kept. A field without
+// a default is written as "key:". When commented is true, every line is commented out.
+func formatFieldValue(indent string, commented bool, key string, value any) (string, error) {
+	if value == nil {
+		return commentPrefix(indent, commented) + key + ":", nil
+	}
+
+	valueNode, err := valueToNode(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode default value of %s: %w", key, err)
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: key},
+		valueNode,
+	}}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return "", fmt.Errorf("failed to encode default value of %s: %w", key, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode default value of %s: %w", key, err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = commentPrefix(indent, commented) + line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func commentPrefix(indent string, commented bool) string {
+	if commented {
+		return indent + "# "
+	}
+	return indent
+}
+
+// valueToNode converts a value decoded from the API into a YAML node. Numbers are
+// written exactly as they appeared in the response, single-line strings are
+// double-quoted (so that values such as "90" or "yes" stay strings) and multi-line
+// strings use a literal block.
+func valueToNode(value any) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case json.Number:
+		// Left untagged: every JSON number is a plain YAML int or float, while an
+		// explicit tag would be written out for integers beyond int64
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v.String()}, nil
 	case string:
-		if v == "" {
-			return "\"\""
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v, Style: yaml.DoubleQuotedStyle}
+		if strings.Contains(v, "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		return node, nil
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			child, err := valueToNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
 		}
-		return fmt.Sprintf("%q", v)
-	case bool:
-		return fmt.Sprintf("%t", v)
-	case float64:
-		return fmt.Sprintf("%v", v)
+		return node, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			child, err := valueToNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, child)
+		}
+		return node, nil
 	default:
-		// For complex types, marshal to JSON
-		b, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Sprintf("%v", v)
+		node := &yaml.Node{}
+		if err := node.Encode(v); err != nil {
+			return nil, err
 		}
-		return string(b)
+		return node, nil
 	}
 }
 
@@ -1211,19 +1652,30 @@ func generateIntegrationInstanceYaml(def IntegrationDefinition) (string, error)
 		return "", err
 	}
 
-	tmpl, err := template.New("instance").Parse(tmplContent)
+	funcMap := template.FuncMap{
+		"fieldFormat":     templateFieldFormat,
+		"normalizeFormat": normalizeFormat,
+	}
+
+	tmpl, err := template.New("instance").Funcs(funcMap).Parse(tmplContent)
 	if err != nil {
 		return "", err
 	}
 
+	configFields := getNonMaskedConfigFields(def)
+
 	data := struct {
+		ChartName                 string
 		IntegrationDefinitionName string
 		ConfigFields              []ConfigField
+		HasRequiredConfigFields   bool
 		HasSecretFields           bool
 		HasAuthSections           bool
 	}{
+		ChartName:                 getChartName(def),
 		IntegrationDefinitionName: def.Name,
-		ConfigFields:              getNonMaskedConfigFields(def),
+		ConfigFields:              configFields,
+		HasRequiredConfigFields:   hasRequiredFields(configFields),
 		HasSecretFields:           hasSecretFields(def),
 		HasAuthSections:           len(def.AuthSections) > 0,
 	}
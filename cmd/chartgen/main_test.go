@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeChartName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"github", "github"},
+		{"Microsoft_Active_Directory", "microsoft-active-directory"},
+		{"graph-kubernetes", "graph-kubernetes"},
+		{"Rapid7 InsightVM", "rapid7insightvm"},
+		{"__jira__", "jira"},
+		{"net--box", "net-box"},
+		{"___", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeChartName(tt.name); got != tt.want {
+				t.Errorf("sanitizeChartName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateChartNames(t *testing.T) {
+	dir := setupWriteTest(t, false)
+	saved := chartgenConf
+	t.Cleanup(func() { chartgenConf = saved })
+
+	writeTestFiles(t, dir, map[string]string{
+		"custom/Chart.yaml": "name: custom\nversion: 0.1.0\n",
+		"github/Chart.yaml": generatedHeader + "\nname: github\nversion: 1.0.0\n",
+	})
+
+	tests := []struct {
+		name        string
+		definitions []string
+		chartNames  map[string]string // chartName overrides by integration name
+		want        []string          // substrings of the error, nil for no error
+	}{
+		{
+			name:        "unique names",
+			definitions: []string{"github", "jira", "net_box"},
+		},
+		{
+			name:        "generated chart",
+			definitions: []string{"GitHub"},
+		},
+		{
+			name:        "collision after sanitizing",
+			definitions: []string{"git_hub", "Git-Hub"},
+			want:        []string{`chart name "git-hub" is used by "git_hub" and "Git-Hub"`},
+		},
+		{
+			name:        "collision resolved by override",
+			definitions: []string{"git_hub", "Git-Hub"},
+			chartNames:  map[string]string{"Git-Hub": "github-enterprise"},
+		},
+		{
+			name:        "reserved chart name",
+			definitions: []string{"jupiterone_integration_runner"},
+			want:        []string{`chart name "jupiterone-integration-runner" is used by "jupiterone-integration-runner" and "jupiterone_integration_runner"`},
+		},
+		{
+			name:        "hand-written chart",
+			definitions: []string{"Custom"},
+			want:        []string{`chart name "custom" is used by "custom" and "Custom"`},
+		},
+		{
+			name:        "too long",
+			definitions: []string{strings.Repeat("a", 54)},
+			want:        []string{"is 54 characters long (max 53)"},
+		},
+		{
+			name:        "empty after sanitizing",
+			definitions: []string{"___"},
+			want:        []string{"___: chart name is empty after sanitizing"},
+		},
+		{
+			name:        "invalid override",
+			definitions: []string{"github"},
+			chartNames:  map[string]string{"github": "GitHub"},
+			want:        []string{`github: chart name "GitHub" must consist of lowercase alphanumerics and hyphens`},
+		},
+		{
+			name:        "all problems reported",
+			definitions: []string{"___", "git_hub", "Git-Hub"},
+			want:        []string{"chart name is empty", `chart name "git-hub" is used by`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartgenConf = ChartgenConfig{Integrations: map[string]IntegrationOverrides{}}
+			for name, chartName := range tt.chartNames {
+				chartgenConf.Integrations[name] = IntegrationOverrides{ChartName: chartName}
+			}
+			var definitions []IntegrationDefinition
+			for _, name := range tt.definitions {
+				definitions = append(definitions, IntegrationDefinition{Name: name})
+			}
+
+			err := validateChartNames(definitions)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("validateChartNames() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateChartNames() succeeded, want errors %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validateChartNames() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFailureSummary(t *testing.T) {
+	saved := maxFailures
+	t.Cleanup(func() { maxFailures = saved })
+
+	failures := []chartFailure{
+		{name: "github", err: errors.New("conflicting field keys:\n  key \"a\" is reserved")},
+		{name: "jira", err: errors.New("failed to write values.yaml")},
+	}
+
+	tests := []struct {
+		name        string
+		failures    []chartFailure
+		maxFailures int
+		want        string // "" for no error
+	}{
+		{name: "no failures"},
+		{
+			name:     "failures",
+			failures: failures,
+			want:     "failed to generate 2 chart(s):\n  github: conflicting field keys: key \"a\" is reserved\n  jira: failed to write values.yaml",
+		},
+		{name: "tolerated", failures: failures, maxFailures: 2},
+		{
+			name:        "more than tolerated",
+			failures:    failures,
+			maxFailures: 1,
+			want:        "failed to generate 2 chart(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxFailures = tt.maxFailures
+			err := failureSummary(tt.failures)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("failureSummary() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("failureSummary() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// serveDefinitions serves the given integration definitions as the JupiterOne API for
+// the rest of the test
+func serveDefinitions(t *testing.T, definitions []IntegrationDefinition) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data IntegrationDefinitionsData
+		data.IntegrationDefinitions.Definitions = definitions
+		if err := json.NewEncoder(w).Encode(map[string]any{"data": data}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("J1_GRAPHQL_ENDPOINT", server.URL)
+}
+
+// setupRunTest resets the run state and sends output to a temporary directory. It
+// returns the output directory.
+func setupRunTest(t *testing.T) string {
+	t.Helper()
+
+	savedOutputDir, savedWrite, savedProgress := outputDir, write, progress
+	savedFailFast, savedMaxFailures, savedRollback := failFast, maxFailures, rollbackOnFailure
+	savedIncludes, savedExcludes, savedName := includePatterns, excludePatterns, integrationName
+	t.Cleanup(func() {
+		outputDir, write, progress = savedOutputDir, savedWrite, savedProgress
+		failFast, maxFailures, rollbackOnFailure = savedFailFast, savedMaxFailures, savedRollback
+		includePatterns, excludePatterns, integrationName = savedIncludes, savedExcludes, savedName
+		runReport = RunReport{}
+		chartgenConf = ChartgenConfig{}
+	})
+
+	outputDir = t.TempDir()
+	write = true
+	progress = io.Discard
+	failFast, maxFailures, rollbackOnFailure = false, 0, false
+	includePatterns, excludePatterns, integrationName = nil, nil, ""
+	runReport = RunReport{}
+	return outputDir
+}
+
+// reportStatuses returns the status of every integration in the run report
+func reportStatuses() map[string]string {
+	statuses := make(map[string]string)
+	for _, chart := range runReport.Charts {
+		statuses[chart.Integration] = chart.Status
+	}
+	return statuses
+}
+
+func TestRunChartGenFailures(t *testing.T) {
+	definition := func(name string) IntegrationDefinition {
+		def := testDefinition()
+		def.ID, def.Name = name, name
+		return def
+	}
+	broken := definition("broken")
+	broken.ConfigFields = append(broken.ConfigFields, ConfigField{Key: "api-url", Type: "string"})
+
+	tests := []struct {
+		name        string
+		failFast    bool
+		maxFailures int
+		wantErr     bool
+		want        map[string]string
+	}{
+		{
+			name:    "failure reported after all charts",
+			wantErr: true,
+			want:    map[string]string{"alpha": statusGenerated, "broken": statusFailed, "omega": statusGenerated},
+		},
+		{
+			name:        "failure tolerated",
+			maxFailures: 1,
+			want:        map[string]string{"alpha": statusGenerated, "broken": statusFailed, "omega": statusGenerated},
+		},
+		{
+			name:     "fail fast",
+			failFast: true,
+			wantErr:  true,
+			want:     map[string]string{"alpha": statusGenerated, "broken": statusFailed, "omega": statusSkipped},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRunTest(t)
+			serveDefinitions(t, []IntegrationDefinition{definition("alpha"), broken, definition("omega")})
+			failFast, maxFailures = tt.failFast, tt.maxFailures
+
+			err := runChartGen(rootCmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runChartGen() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if got := reportStatuses(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statuses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunChartGenChartNameCollision(t *testing.T) {
+	definition := func(name string) IntegrationDefinition {
+		def := testDefinition()
+		def.ID, def.Name = name, name
+		return def
+	}
+
+	tests := []struct {
+		name     string
+		excludes []string
+		wantErr  bool
+		want     map[string]string
+	}{
+		{
+			name:    "both selected",
+			wantErr: true,
+			want:    map[string]string{"git_hub": statusFailed, "Git-Hub": statusFailed},
+		},
+		{
+			name:     "one excluded",
+			excludes: []string{"Git-Hub"},
+			want:     map[string]string{"git_hub": statusUnchanged, "Git-Hub": statusSkipped},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRunTest(t)
+			serveDefinitions(t, []IntegrationDefinition{definition("git_hub"), definition("Git-Hub")})
+			excludePatterns = tt.excludes
+
+			// The second run regenerates the chart written by the first
+			for run := 1; run <= 2; run++ {
+				runReport = RunReport{}
+				err := runChartGen(rootCmd, nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("run %d: runChartGen() error = %v, want error: %v", run, err, tt.wantErr)
+				}
+			}
+			if got := reportStatuses(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statuses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentLines(t *testing.T) {
+	long := "The API token of a service account with read access to every repository in the organization."
+
+	tests := []struct {
+		name   string
+		indent string
+		text   string
+		want   string
+	}{
+		{name: "empty", text: "", want: ""},
+		{name: "single line", text: "Organization name", want: "# Organization name"},
+		{name: "indented", indent: "  ", text: "Organization name", want: "  # Organization name"},
+		{
+			name: "line endings",
+			text: "first\r\nsecond\rthird\n",
+			want: "# first\n# second\n# third",
+		},
+		{
+			name: "blank lines collapsed",
+			text: "\n\nfirst\n\n  \n\nsecond\n \n",
+			want: "# first\n#\n# second",
+		},
+		{
+			name: "wrapped",
+			text: long,
+			want: "# The API token of a service account with read access to every repository in the\n# organization.",
+		},
+		{
+			name:   "wrapped with indentation",
+			indent: "    ",
+			text:   long,
+			want:   "    # The API token of a service account with read access to every repository in\n    # the organization.",
+		},
+		{
+			name: "list items keep their indentation",
+			text: "Scopes:\n  - repo: " + long,
+			want: "# Scopes:\n#   - repo: The API token of a service account with read access to every\n#   repository in the organization.",
+		},
+		{
+			name: "tabs",
+			text: "Scopes:\n\trepo",
+			want: "# Scopes:\n#   repo",
+		},
+		{
+			name: "word longer than the width",
+			text: "See " + strings.Repeat("x", 90) + " for details",
+			want: "# See\n# " + strings.Repeat("x", 90) + "\n# for details",
+		},
+		{
+			name: "YAML in the text stays commented",
+			text: "Example:\nkey: value\n---\n- item",
+			want: "# Example:\n# key: value\n# ---\n# - item",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commentLines(tt.indent, tt.text)
+			if got != tt.want {
+				t.Errorf("commentLines(%q, %q) =\n%s\nwant:\n%s", tt.indent, tt.text, got, tt.want)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if got != "" && len(line) > commentWidth && !strings.Contains(line, strings.Repeat("x", 90)) {
+					t.Errorf("line %q is longer than %d characters", line, commentWidth)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateChartWithMultiLineDescriptions(t *testing.T) {
+	def := testDefinition()
+	def.ConfigFields[0].Description = "The organization.\r\n\r\nExample:\n  organization: acme\n---\n- not a list item\n  "
+	def.ConfigFields[0].HelperText = strings.Repeat("Long helper text. ", 10)
+
+	// generateChart fails when the generated values.yaml does not parse
+	values := readTestFiles(t, generateTestChart(t, def))["values.yaml"]
+	if !strings.Contains(values, "# ---\n") || !strings.Contains(values, "#   organization: acme\n") {
+		t.Errorf("description is not commented out in values.yaml:\n%s", values)
+	}
+}
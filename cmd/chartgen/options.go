@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Multiselect reports whether the field takes a list of its options rather than one
+func (cf ConfigField) Multiselect() bool {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(cf.Type)
+	return strings.EqualFold(normalized, "multiselect")
+}
+
+// isNumericType reports whether the field's values are numbers
+func (cf ConfigField) isNumericType() bool {
+	return cf.Type == "number" || cf.Type == "integer"
+}
+
+// optionLabel returns the label of an option, or "" when it adds nothing to the value
+func optionLabel(opt ConfigOption) string {
+	label := strings.TrimSpace(opt.Label)
+	if label == opt.Value {
+		return ""
+	}
+	return label
+}
+
+// describeOptions returns the options of a field as text for a values.yaml comment, with
+// one "value: label" line per option
+func describeOptions(cf ConfigField) string {
+	var b strings.Builder
+	if cf.Multiselect() {
+		b.WriteString("Options (a list of any of these values):")
+	} else {
+		b.WriteString("Options:")
+	}
+	for _, opt := range cf.Options {
+		b.WriteString("\n  " + opt.Value)
+		if label := optionLabel(opt); label != "" {
+			b.WriteString(": " + label)
+		}
+	}
+	return b.String()
+}
+
+// optionValues returns the values of a field's options
+func optionValues(cf ConfigField) []string {
+	values := make([]string, 0, len(cf.Options))
+	for _, opt := range cf.Options {
+		values = append(values, opt.Value)
+	}
+	return values
+}
+
+// optionSchemaValues returns the values of a field's options as they may appear in
+// values.yaml. Options are strings in the API; numeric fields accept them as numbers
+// too.
+func optionSchemaValues(cf ConfigField, opt ConfigOption) []any {
+	values := []any{opt.Value}
+	if cf.isNumericType() {
+		var number json.Number
+		if err := json.Unmarshal([]byte(opt.Value), &number); err == nil {
+			values = append(values, number)
+		}
+	}
+	return values
+}
+
+// addOptionsSchema restricts a field's schema to its options. Labels are recorded
+// under x-enum-labels, keyed by value. Multi-select fields are lists of options. Like
+// pollingInterval, other fields also accept "" and null, which the templates treat as
+// unset, so that the "" placeholder of a required field passes and its required check
+// reports the missing value.
+func addOptionsSchema(schema *JSONSchema, cf ConfigField) {
+	values := &JSONSchema{EnumLabels: map[string]string{}}
+	for _, opt := range cf.Options {
+		values.Enum = append(values.Enum, optionSchemaValues(cf, opt)...)
+		if label := optionLabel(opt); label != "" {
+			values.EnumLabels[opt.Value] = label
+		}
+	}
+	if len(values.EnumLabels) == 0 {
+		values.EnumLabels = nil
+	}
+
+	if cf.Multiselect() {
+		schema.Type = []string{"array", "null"}
+		schema.Format = ""
+		schema.Pattern = ""
+		schema.Items = values
+		return
+	}
+	schema.Enum = values.Enum
+	if !containsString(optionValues(cf), "") {
+		schema.Enum = append(schema.Enum, "")
+	}
+	schema.Enum = append(schema.Enum, nil)
+	schema.EnumLabels = values.EnumLabels
+}
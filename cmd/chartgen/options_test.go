@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultiselect(t *testing.T) {
+	tests := []struct {
+		fieldType string
+		want      bool
+	}{
+		{"multiselect", true},
+		{"multiSelect", true},
+		{"multi-select", true},
+		{"multi_select", true},
+		{"select", false},
+		{"string", false},
+	}
+
+	for _, tt := range tests {
+		if got := (ConfigField{Type: tt.fieldType}).Multiselect(); got != tt.want {
+			t.Errorf("Multiselect() of type %q = %v, want %v", tt.fieldType, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeOptions(t *testing.T) {
+	options := []ConfigOption{
+		{Label: "United States", Value: "us"},
+		{Label: "eu", Value: "eu"},
+		{Label: "  ", Value: "asia"},
+	}
+
+	tests := []struct {
+		name  string
+		field ConfigField
+		want  string
+	}{
+		{
+			name:  "single-select",
+			field: ConfigField{Type: "string", Options: options},
+			want:  "Options:\n  us: United States\n  eu\n  asia",
+		},
+		{
+			name:  "multi-select",
+			field: ConfigField{Type: "multiselect", Options: options},
+			want:  "Options (a list of any of these values):\n  us: United States\n  eu\n  asia",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeOptions(tt.field); got != tt.want {
+				t.Errorf("describeOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddOptionsSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		field ConfigField
+		want  JSONSchema
+	}{
+		{
+			name: "single-select",
+			field: ConfigField{Type: "string", Options: []ConfigOption{
+				{Label: "United States", Value: "us"}, {Label: "eu", Value: "eu"},
+			}},
+			want: JSONSchema{
+				Enum:       []any{"us", "eu", "", nil},
+				EnumLabels: map[string]string{"us": "United States"},
+			},
+		},
+		{
+			name: "single-select with an empty option",
+			field: ConfigField{Type: "string", Options: []ConfigOption{
+				{Label: "None", Value: ""}, {Value: "us"},
+			}},
+			want: JSONSchema{
+				Enum:       []any{"", "us", nil},
+				EnumLabels: map[string]string{"": "None"},
+			},
+		},
+		{
+			name: "number",
+			field: ConfigField{Type: "number", Options: []ConfigOption{
+				{Value: "1"}, {Value: "many"},
+			}},
+			want: JSONSchema{
+				Enum: []any{"1", json.Number("1"), "many", "", nil},
+			},
+		},
+		{
+			name: "multi-select",
+			field: ConfigField{Type: "multiselect", Options: []ConfigOption{
+				{Label: "Open", Value: "open"}, {Value: "closed"},
+			}},
+			want: JSONSchema{
+				Type: []string{"array", "null"},
+				Items: &JSONSchema{
+					Enum:       []any{"open", "closed"},
+					EnumLabels: map[string]string{"open": "Open"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got JSONSchema
+			addOptionsSchema(&got, tt.field)
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				t.Errorf("addOptionsSchema() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestGenerateChartOptionComments(t *testing.T) {
+	chartDir := generateTestChart(t, configTypesDefinition())
+
+	data, err := os.ReadFile(filepath.Join(chartDir, "values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The config fields of values.yaml, between the Integration Configuration and the
+	// Sensitive Configuration sections
+	values := string(data)
+	start := strings.Index(values, "# Integration Configuration")
+	end := strings.Index(values, "# Sensitive Configuration")
+	if start < 0 || end < start {
+		t.Fatalf("config fields not found in values.yaml:\n%s", values)
+	}
+	assertGolden(t, values[start:end])
+}
+
+func TestRenderOptions(t *testing.T) {
+	chartDir := generateTestChart(t, configTypesDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name:   "required option unset",
+			values: ``,
+		},
+		{
+			name: "required option empty",
+			values: `
+region: ""
+`,
+		},
+		{
+			name: "required option null",
+			values: `
+region: null
+`,
+		},
+		{
+			name: "unknown multi-select option",
+			values: `
+region: us
+states: [open, merged]
+`,
+		},
+		{
+			name: "multi-select option as a string",
+			values: `
+region: us
+states: open
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values + "secret:\n  selectedAuthType: token\n  apiToken: token\n"
+			assertGolden(t, renderTestChart(t, chartDir, values, "integrationinstance.yaml"))
+		})
+	}
+}
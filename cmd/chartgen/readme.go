@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// readmeField is a row of a values table in a generated chart's README
+type readmeField struct {
+	Parameter   string
+	Description string
+	Type        string
+	Required    bool
+	Default     string
+	Options     string
+}
+
+// readmeAuthSection is an auth section with the rows of its fields
+type readmeAuthSection struct {
+	ID          string
+	DisplayName string
+	Fields      []readmeField
+}
+
+// newReadmeFields builds table rows for fields. Hidden fields are left out.
+func newReadmeFields(prefix string, fields []ConfigField) []readmeField {
+	var rows []readmeField
+	for _, cf := range fields {
+		if cf.Hidden {
+			continue
+		}
+
+		description := cf.Description
+		if description == "" {
+			description = cf.DisplayName
+		}
+		if cf.Deprecated != "" {
+			description = "DEPRECATED: " + cf.Deprecated + " " + description
+		}
+
+		fieldType := cf.Type
+		if cf.Multiselect() {
+			fieldType = "list"
+		}
+
+		rows = append(rows, readmeField{
+			Parameter:   prefix + cf.Key,
+			Description: markdownCell(description),
+			Type:        fieldType,
+			Required:    cf.Required(),
+			Default:     readmeDefault(cf.DefaultValue),
+			Options:     readmeOptions(cf),
+		})
+	}
+	return rows
+}
+
+// readmeDefault renders a default value as inline code, or "" when there is none
+func readmeDefault(value any) string {
+	if value == nil {
+		return ""
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return "`" + markdownCell(string(b)) + "`"
+}
+
+// readmeOptions renders the options of a field as a list of values with their labels
+func readmeOptions(cf ConfigField) string {
+	var options []string
+	for _, opt := range cf.Options {
+		option := "`" + markdownCell(opt.Value) + "`"
+		if label := optionLabel(opt); label != "" {
+			option += fmt.Sprintf(" (%s)", markdownCell(label))
+		}
+		options = append(options, option)
+	}
+	if len(options) > 0 && cf.Multiselect() {
+		return "Any of: " + strings.Join(options, ", ")
+	}
+	return strings.Join(options, ", ")
+}
+
+func generateReadme(def IntegrationDefinition) (string, error) {
+	var authSections []readmeAuthSection
+	for _, as := range getFlattenedAuthSections(def) {
+		authSections = append(authSections, readmeAuthSection{
+			ID:          as.ID,
+			DisplayName: as.DisplayName,
+			Fields:      newReadmeFields("secret.", as.ConfigFields),
+		})
+	}
+
+	data := struct {
+		chartTemplateData
+		IntegrationDefinitionName string
+		RequiredInputs            []requiredInput
+		ConfigFields              []readmeField
+		MaskedConfigFields        []readmeField
+		AuthSections              []readmeAuthSection
+	}{
+		chartTemplateData:         newChartTemplateData(def),
+		IntegrationDefinitionName: def.Name,
+		RequiredInputs:            getRequiredInputs(def),
+		ConfigFields:              newReadmeFields("", getNonMaskedConfigFields(def)),
+		MaskedConfigFields:        newReadmeFields("secret.", getMaskedConfigFields(def)),
+		AuthSections:              authSections,
+	}
+
+	return renderTemplate("README.md.tmpl", data)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Statuses of a definition in the run report
+const (
+	statusGenerated     = "generated"
+	statusWouldGenerate = "would-generate"
+	statusUnchanged     = "unchanged"
+	statusSkipped       = "skipped"
+	statusFailed        = "failed"
+	statusRolledBack    = "rolled-back"
+)
+
+// Supported --report formats
+const (
+	reportFormatJSON     = "json"
+	reportFormatMarkdown = "markdown"
+)
+
+var (
+	reportFormat string
+	reportFile   string
+	runReport    RunReport
+
+	// progress receives the human-readable output of a run. It is stderr when the
+	// report is written to stdout, so that the report can be parsed.
+	progress io.Writer = os.Stdout
+)
+
+// RunReport describes what happened to every integration definition considered in a run
+type RunReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Summary map[string]int `json:"summary"`
+	Charts  []ChartReport  `json:"charts"`
+}
+
+// ChartReport is the outcome for a single integration definition
+type ChartReport struct {
+	Integration  string        `json:"integration"`
+	Chart        string        `json:"chart,omitempty"`
+	Status       string        `json:"status"`
+	Reason       string        `json:"reason,omitempty"`
+	OldVersion   string        `json:"oldVersion,omitempty"`
+	NewVersion   string        `json:"newVersion,omitempty"`
+	FieldChanges []FieldChange `json:"fieldChanges,omitempty"`
+	Warnings     []string      `json:"warnings,omitempty"`
+}
+
+// FieldChange is a field added to, removed from or moved within a chart. Config fields
+// are top-level values; secret fields are keys of the generated Secret.
+type FieldChange struct {
+	Key    string `json:"key"`
+	Change string `json:"change"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// validateReportFormat checks the --report flag
+func validateReportFormat() error {
+	switch reportFormat {
+	case "", reportFormatJSON, reportFormatMarkdown:
+		return nil
+	default:
+		return fmt.Errorf("invalid report format %q (must be %s or %s)", reportFormat, reportFormatJSON, reportFormatMarkdown)
+	}
+}
+
+// redirectProgress sends progress output to stderr when the report goes to stdout
+func redirectProgress() {
+	if reportFormat != "" && reportFile == "" {
+		progress = os.Stderr
+	}
+}
+
+// addChartReport records the outcome for a definition
+func addChartReport(report ChartReport) {
+	runReport.Charts = append(runReport.Charts, report)
+}
+
+// markRolledBack marks every generated chart in the report as rolled back
+func markRolledBack() {
+	for i := range runReport.Charts {
+		if runReport.Charts[i].Status == statusGenerated {
+			runReport.Charts[i].Status = statusRolledBack
+		}
+	}
+}
+
+var (
+	// Matches .Values.<key> references in the config block of integrationinstance.yaml
+	configValuePattern = regexp.MustCompile(`\.Values\.([A-Za-z_][A-Za-z0-9_]*)`)
+	// Matches .Values.secret.<key> references in secret.yaml
+	secretValuePattern = regexp.MustCompile(`\.Values\.secret\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// chartFieldKeys returns the fields used by a chart's templates, mapped to "config" or
+// "secret". It reads the rendered templates so that the same logic applies to the
+// existing chart on disk and the newly generated one.
+func chartFieldKeys(instanceYaml string, secretYaml string) map[string]string {
+	keys := make(map[string]string)
+
+	if i := strings.Index(instanceYaml, "\n  config:"); i >= 0 {
+		for _, m := range configValuePattern.FindAllStringSubmatch(instanceYaml[i:], -1) {
+			keys[m[1]] = "config"
+		}
+	}
+	for _, m := range secretValuePattern.FindAllStringSubmatch(secretYaml, -1) {
+		if !containsString(reservedSecretKeys, m[1]) && !containsString(secretSettingKeys, m[1]) {
+			keys[m[1]] = "secret"
+		}
+	}
+
+	return keys
+}
+
+// diffChartFields compares the fields of the existing chart with the generated files
+func diffChartFields(chartDir string, files map[string]string) []FieldChange {
+	oldKeys := chartFieldKeys(
+		readFileIfExists(filepath.Join(chartDir, "templates", "integrationinstance.yaml")),
+		readFileIfExists(filepath.Join(chartDir, "templates", "secret.yaml")),
+	)
+	newKeys := chartFieldKeys(files["templates/integrationinstance.yaml"], files["templates/secret.yaml"])
+
+	var changes []FieldChange
+	for key, to := range newKeys {
+		from, ok := oldKeys[key]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Key: key, Change: "added", To: to})
+		case from != to:
+			changes = append(changes, FieldChange{Key: key, Change: "moved", From: from, To: to})
+		}
+	}
+	for key, from := range oldKeys {
+		if _, ok := newKeys[key]; !ok {
+			changes = append(changes, FieldChange{Key: key, Change: "removed", From: from})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// writeRunReport writes the run report in the --report format to --report-file, or to
+// stdout when no file is given
+func writeRunReport() error {
+	if reportFormat == "" {
+		return nil
+	}
+
+	runReport.DryRun = !write
+	runReport.Summary = make(map[string]int)
+	for _, chart := range runReport.Charts {
+		runReport.Summary[chart.Status]++
+	}
+	sort.SliceStable(runReport.Charts, func(i, j int) bool {
+		return runReport.Charts[i].Integration < runReport.Charts[j].Integration
+	})
+
+	var content string
+	if reportFormat == reportFormatJSON {
+		b, err := json.MarshalIndent(runReport, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		content = string(b) + "\n"
+	} else {
+		content = formatMarkdownReport(runReport)
+	}
+
+	if reportFile == "" {
+		fmt.Print(content)
+		return nil
+	}
+	if err := os.WriteFile(reportFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func formatMarkdownReport(report RunReport) string {
+	var b strings.Builder
+
+	b.WriteString("# chartgen report\n\n")
+	if report.DryRun {
+		b.WriteString("Dry run: no files were written.\n\n")
+	}
+
+	statuses := []string{statusGenerated, statusWouldGenerate, statusUnchanged, statusSkipped, statusFailed, statusRolledBack}
+	var summary []string
+	for _, status := range statuses {
+		if n := report.Summary[status]; n > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+	if len(summary) == 0 {
+		summary = append(summary, "no integrations")
+	}
+	fmt.Fprintf(&b, "Summary: %s\n\n", strings.Join(summary, ", "))
+
+	b.WriteString("| Integration | Chart | Status | Version | Notes |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, chart := range report.Charts {
+		version := chart.NewVersion
+		if chart.OldVersion != "" && chart.OldVersion != chart.NewVersion {
+			version = chart.OldVersion + " → " + chart.NewVersion
+		}
+		notes := chart.Reason
+		if len(chart.FieldChanges) > 0 {
+			if notes != "" {
+				notes += "; "
+			}
+			notes += fmt.Sprintf("%d field changes", len(chart.FieldChanges))
+		}
+		if len(chart.Warnings) > 0 {
+			if notes != "" {
+				notes += "; "
+			}
+			notes += fmt.Sprintf("%d warnings", len(chart.Warnings))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			markdownCell(chart.Integration), markdownCell(chart.Chart), chart.Status, version, markdownCell(notes))
+	}
+
+	for _, chart := range report.Charts {
+		if len(chart.FieldChanges) == 0 && len(chart.Warnings) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", chart.Chart)
+		for _, warning := range chart.Warnings {
+			fmt.Fprintf(&b, "- Warning: %s\n", warning)
+		}
+		for _, change := range chart.FieldChanges {
+			switch change.Change {
+			case "added":
+				fmt.Fprintf(&b, "- `%s` added (%s)\n", change.Key, change.To)
+			case "removed":
+				fmt.Fprintf(&b, "- `%s` removed (%s)\n", change.Key, change.From)
+			default:
+				fmt.Fprintf(&b, "- `%s` moved from %s to %s\n", change.Key, change.From, change.To)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// markdownCell escapes a value for use in a markdown table cell
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
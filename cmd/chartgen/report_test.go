@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChartFieldKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceYaml string
+		secretYaml   string
+		want         map[string]string
+	}{
+		{
+			name:         "values outside the config block are ignored",
+			instanceYaml: "spec:\n  collectorName: {{ .Values.collectorName }}\n  config:\n    host: {{ .Values.host | quote }}\n",
+			want:         map[string]string{"host": "config"},
+		},
+		{
+			name:       "secret settings and selectedAuthType are ignored",
+			secretYaml: "{{ .Values.secret.labels }}{{ .Values.secret.selectedAuthType }}{{ .Values.secret.apiToken }}",
+			want:       map[string]string{"apiToken": "secret"},
+		},
+		{
+			name: "no chart",
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chartFieldKeys(tt.instanceYaml, tt.secretYaml); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chartFieldKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartFieldKeysOfGeneratedChart(t *testing.T) {
+	files := readTestFiles(t, generateTestChart(t, testDefinition()))
+
+	got := chartFieldKeys(files["templates/integrationinstance.yaml"], files["templates/secret.yaml"])
+	want := map[string]string{"organization": "config", "maxPages": "config", "apiToken": "secret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chartFieldKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffChartFields(t *testing.T) {
+	oldChartDir := generateTestChart(t, testDefinition())
+
+	def := testDefinition()
+	def.ConfigFields = []ConfigField{
+		{Key: "organization", Type: "string"},
+		{Key: "baseUrl", Type: "string", Optional: true},
+		{Key: "maxPages", Type: "number", Mask: true},
+	}
+	files := readTestFiles(t, generateTestChart(t, def))
+
+	got := diffChartFields(oldChartDir, files)
+	want := []FieldChange{
+		{Key: "baseUrl", Change: "added", To: "config"},
+		{Key: "maxPages", Change: "moved", From: "config", To: "secret"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffChartFields() = %+v, want %+v", got, want)
+	}
+
+	got = diffChartFields(t.TempDir(), files)
+	if len(got) != 4 {
+		t.Errorf("diffChartFields() for a new chart = %+v, want every field added", got)
+	}
+
+	got = diffChartFields(oldChartDir, map[string]string{})
+	want = []FieldChange{
+		{Key: "apiToken", Change: "removed", From: "secret"},
+		{Key: "maxPages", Change: "removed", From: "config"},
+		{Key: "organization", Change: "removed", From: "config"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffChartFields() for removed fields = %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerateChartReportStatus(t *testing.T) {
+	dir := setupWriteTest(t, false)
+	savedWrite := write
+	t.Cleanup(func() { write = savedWrite })
+
+	def := testDefinition()
+	steps := []struct {
+		name  string
+		write bool
+		want  ChartReport
+	}{
+		{name: "dry run", write: false, want: ChartReport{Status: statusWouldGenerate, NewVersion: "1.0.1"}},
+		{name: "write", write: true, want: ChartReport{Status: statusGenerated, NewVersion: "1.0.1"}},
+		{name: "write unchanged", write: true, want: ChartReport{Status: statusUnchanged, OldVersion: "1.0.1", NewVersion: "1.0.1"}},
+	}
+
+	for _, step := range steps {
+		write = step.write
+		var result ChartReport
+		if err, _ := generateChart(def, &result); err != nil {
+			t.Fatalf("%s: generateChart: %v", step.name, err)
+		}
+		got := ChartReport{Status: result.Status, OldVersion: result.OldVersion, NewVersion: result.NewVersion}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: report = %+v, want %+v", step.name, got, step.want)
+		}
+
+		_, err := os.Stat(filepath.Join(dir, def.Name))
+		if exists := err == nil; exists != step.write {
+			t.Errorf("%s: chart exists = %v, want %v", step.name, exists, step.write)
+		}
+	}
+}
+
+func TestFormatMarkdownReport(t *testing.T) {
+	report := RunReport{
+		DryRun:  true,
+		Summary: map[string]int{statusWouldGenerate: 1, statusFailed: 1},
+		Charts: []ChartReport{
+			{
+				Integration:  "example",
+				Chart:        "example",
+				Status:       statusWouldGenerate,
+				OldVersion:   "1.0.0",
+				NewVersion:   "1.0.1",
+				FieldChanges: []FieldChange{{Key: "baseUrl", Change: "added", To: "config"}, {Key: "maxPages", Change: "moved", From: "config", To: "secret"}},
+				Warnings:     []string{"unknown format"},
+			},
+			{Integration: "broken", Chart: "broken", Status: statusFailed, Reason: "bad | key\nin chart"},
+		},
+	}
+
+	want := "# chartgen report\n\n" +
+		"Dry run: no files were written.\n\n" +
+		"Summary: 1 would-generate, 1 failed\n\n" +
+		"| Integration | Chart | Status | Version | Notes |\n" +
+		"|---|---|---|---|---|\n" +
+		"| example | example | would-generate | 1.0.0 → 1.0.1 | 2 field changes; 1 warnings |\n" +
+		"| broken | broken | failed |  | bad \\| key in chart |\n" +
+		"\n## example\n\n" +
+		"- Warning: unknown format\n" +
+		"- `baseUrl` added (config)\n" +
+		"- `maxPages` moved from config to secret\n"
+	if got := formatMarkdownReport(report); got != want {
+		t.Errorf("formatMarkdownReport() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import "testing"
+
+func TestRenderSchedule(t *testing.T) {
+	chartDir := generateTestChart(t, testDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+		// skipSchema tests the render-time checks behind values.schema.json
+		skipSchema bool
+	}{
+		{
+			name:   "default interval",
+			values: "",
+		},
+		{
+			name: "interval",
+			values: `
+pollingInterval: ONE_HOUR
+`,
+		},
+		{
+			name: "cron only",
+			values: `
+pollingIntervalCron:
+  hour: 2
+  dayOfWeek: 1
+`,
+		},
+		{
+			name: "both set",
+			values: `
+pollingInterval: ONE_DAY
+pollingIntervalCron:
+  hour: 2
+  dayOfWeek: 1
+`,
+		},
+		{
+			name: "unknown interval",
+			values: `
+pollingInterval: TWO_DAYS
+`,
+			skipSchema: true,
+		},
+		{
+			name: "cron hour out of range",
+			values: `
+pollingIntervalCron:
+  hour: 24
+  dayOfWeek: 1
+`,
+			skipSchema: true,
+		},
+		{
+			name: "cron hour not a number",
+			values: `
+pollingIntervalCron:
+  hour: "2"
+  dayOfWeek: 1
+`,
+			skipSchema: true,
+		},
+		{
+			name: "cron hour not an integer",
+			values: `
+pollingIntervalCron:
+  hour: 2.5
+  dayOfWeek: 1
+`,
+			skipSchema: true,
+		},
+		{
+			name: "instance cron replaces top-level interval",
+			values: `
+pollingInterval: ONE_DAY
+instances:
+  - name: example-a
+    pollingIntervalCron:
+      hour: 3
+      dayOfWeek: 2
+  - name: example-b
+`,
+		},
+		{
+			name: "instance interval replaces top-level cron",
+			values: `
+pollingIntervalCron:
+  hour: 2
+  dayOfWeek: 1
+instances:
+  - name: example-a
+    pollingInterval: ONE_HOUR
+  - name: example-b
+`,
+		},
+		{
+			name: "instance sets both",
+			values: `
+instances:
+  - name: example-a
+    pollingInterval: ONE_HOUR
+    pollingIntervalCron:
+      hour: 2
+      dayOfWeek: 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values + "\norganization: acme\nsecret:\n  selectedAuthType: token\n  apiToken: token\n"
+			if tt.skipSchema {
+				assertGolden(t, renderTestChartWithoutSchema(t, chartDir, values, "integrationinstance.yaml"))
+				return
+			}
+			assertGolden(t, renderTestChart(t, chartDir, values, "integrationinstance.yaml"))
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONSchema is the subset of JSON Schema (draft-07) used for values.schema.json
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        any                    `json:"type,omitempty"`
+	Enum        []any                  `json:"enum,omitempty"`
+	EnumLabels  map[string]string      `json:"x-enum-labels,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Pattern     string                 `json:"pattern,omitempty"`
+	MinLength   *int                   `json:"minLength,omitempty"`
+	Minimum     *int                   `json:"minimum,omitempty"`
+	Maximum     *int                   `json:"maximum,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	If          *JSONSchema            `json:"if,omitempty"`
+	Else        *JSONSchema            `json:"else,omitempty"`
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// pollingIntervalSchema allows any of pollingIntervals, or an empty value when pollingIntervalCron is used
+func pollingIntervalSchema() *JSONSchema {
+	enum := make([]any, 0, len(pollingIntervals)+2)
+	for _, interval := range pollingIntervals {
+		enum = append(enum, interval)
+	}
+	enum = append(enum, "", nil)
+
+	return &JSONSchema{
+		Description: "How often the integration should run (leave empty when using pollingIntervalCron)",
+		Type:        []string{"string", "null"},
+		Enum:        enum,
+	}
+}
+
+func pollingIntervalCronSchema() *JSONSchema {
+	return &JSONSchema{
+		Description: "Cron schedule used instead of pollingInterval",
+		Type:        []string{"object", "null"},
+		Properties: map[string]*JSONSchema{
+			"hour": {
+				Description: "Hour of the day",
+				Type:        "integer",
+				Minimum:     intPtr(0),
+				Maximum:     intPtr(cronHourMax),
+			},
+			"dayOfWeek": {
+				Description: "Day of the week",
+				Type:        "integer",
+				Minimum:     intPtr(0),
+				Maximum:     intPtr(cronDayOfWeekMax),
+			},
+		},
+		Required: []string{"hour", "dayOfWeek"},
+	}
+}
+
+// fieldFormatSchema returns the schema of a field with a known format, or nil. Null is
+// allowed so that unset fields and required placeholders pass; required checks in the
+// templates report missing values.
+func fieldFormatSchema(cf ConfigField) *JSONSchema {
+	format, ok := lookupFieldFormat(cf.Format)
+	if !ok {
+		return nil
+	}
+
+	schema := &JSONSchema{
+		Type:    []string{"string", "null"},
+		Format:  format.SchemaFormat,
+		Pattern: format.Pattern,
+	}
+	if format.SchemaType != "" {
+		schema.Type = []string{format.SchemaType, "string", "null"}
+	}
+	return schema
+}
+
+// fieldSchema returns the schema of a field with a known format or options, or nil
+func fieldSchema(cf ConfigField) *JSONSchema {
+	schema := fieldFormatSchema(cf)
+	if len(cf.Options) > 0 {
+		if schema == nil {
+			schema = &JSONSchema{}
+		}
+		addOptionsSchema(schema, cf)
+	}
+	return schema
+}
+
+// addFieldSchemas adds the schemas of config fields to a values object, and the schemas
+// of masked config fields and auth fields to its secret property
+func addFieldSchemas(values *JSONSchema, def IntegrationDefinition) {
+	reg := newFieldRegistry(def)
+
+	for _, cf := range reg.fieldsOf(fieldConfig) {
+		if schema := fieldSchema(cf); schema != nil {
+			values.Properties[cf.Key] = schema
+		}
+	}
+
+	fields := reg.fieldsOf(fieldMasked, fieldAuth)
+	if len(fields) == 0 {
+		return
+	}
+
+	secret := &JSONSchema{
+		Type: []string{"object", "null"},
+		Properties: map[string]*JSONSchema{
+			"labels":          {Type: []string{"object", "null"}},
+			"annotations":     {Type: []string{"object", "null"}},
+			"keepOnUninstall": {Type: []string{"boolean", "null"}},
+			"immutable":       {Type: []string{"boolean", "null"}},
+			"preEncoded":      {Type: []string{"boolean", "null"}},
+		},
+	}
+	// Pre-encoded values are base64, so their formats are checked after decoding when
+	// rendering
+	fieldSchemas := map[string]*JSONSchema{}
+	for _, cf := range fields {
+		if schema := fieldSchema(cf); schema != nil {
+			fieldSchemas[cf.Key] = schema
+		}
+	}
+	if len(fieldSchemas) > 0 {
+		secret.If = preEncodedSchema()
+		secret.Else = &JSONSchema{Properties: fieldSchemas}
+	}
+	values.Properties["secret"] = secret
+}
+
+// preEncodedSchema matches a secret object whose preEncoded setting is true
+func preEncodedSchema() *JSONSchema {
+	return &JSONSchema{
+		Type:       "object",
+		Properties: map[string]*JSONSchema{"preEncoded": {Enum: []any{true}}},
+		Required:   []string{"preEncoded"},
+	}
+}
+
+// secretBackendSchema restricts secretBackend.provider to the known backends. The
+// settings of each backend are checked when rendering.
+func secretBackendSchema() *JSONSchema {
+	var providers []any
+	for _, backend := range secretBackends {
+		providers = append(providers, backend.Name)
+	}
+	return &JSONSchema{
+		Type: []string{"object", "null"},
+		Properties: map[string]*JSONSchema{
+			"provider": {
+				Description: "Backend that provides the secret referenced by secretName",
+				Type:        []string{"string", "null"},
+				Enum:        append(providers, nil),
+			},
+		},
+	}
+}
+
+// getValuesSchema builds the JSON schema for a generated chart's values.yaml.
+// Unknown keys are allowed so that values files keep working as fields are added.
+func getValuesSchema(def IntegrationDefinition) *JSONSchema {
+	instance := &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"name":                {Type: "string", MinLength: intPtr(1)},
+			"pollingInterval":     pollingIntervalSchema(),
+			"pollingIntervalCron": pollingIntervalCronSchema(),
+		},
+	}
+
+	addFieldSchemas(instance, def)
+	if hasSecretFields(def) {
+		instance.Properties["secretBackend"] = secretBackendSchema()
+	}
+
+	values := &JSONSchema{
+		Schema: "https://json-schema.org/draft-07/schema#",
+		Title:  fmt.Sprintf("Values for the JupiterOne %s integration chart", def.Title),
+		Type:   "object",
+		Properties: map[string]*JSONSchema{
+			"collectorName":       {Type: "string", MinLength: intPtr(1)},
+			"pollingInterval":     pollingIntervalSchema(),
+			"pollingIntervalCron": pollingIntervalCronSchema(),
+			"resourceGroupId":     {Type: []string{"string", "null"}},
+			"integrationInstanceId": {
+				Description: "ID of an existing JupiterOne integration instance to adopt",
+				Type:        []string{"string", "null"},
+			},
+			"instances":         {Type: "array", Items: instance},
+			"commonLabels":      {Type: []string{"object", "null"}},
+			"commonAnnotations": {Type: []string{"object", "null"}},
+			"test": {
+				Type: "object",
+				Properties: map[string]*JSONSchema{
+					"enabled":        {Type: "boolean"},
+					"timeoutSeconds": {Type: "integer", Minimum: intPtr(1)},
+					"image":          {Type: "string", MinLength: intPtr(1)},
+				},
+			},
+		},
+	}
+	addFieldSchemas(values, def)
+	if hasSecretFields(def) {
+		values.Properties["secretBackend"] = secretBackendSchema()
+	}
+
+	// Instances inherit preEncoded from the top-level secret, so the secret values of
+	// an instance are only checked when it is not set there
+	if secret := instance.Properties["secret"]; secret != nil && secret.Else != nil {
+		values.If = &JSONSchema{
+			Properties: map[string]*JSONSchema{"secret": preEncodedSchema()},
+			Required:   []string{"secret"},
+		}
+		values.Else = &JSONSchema{
+			Properties: map[string]*JSONSchema{
+				"instances": {Items: &JSONSchema{
+					Properties: map[string]*JSONSchema{"secret": {If: secret.If, Else: secret.Else}},
+				}},
+			},
+		}
+		secret.If, secret.Else = nil, nil
+	}
+
+	return values
+}
+
+func generateValuesSchema(def IntegrationDefinition) (string, error) {
+	b, err := json.MarshalIndent(getValuesSchema(def), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// secretBackend is a way of providing the Secret that the IntegrationInstance
+// references with secretRef. Every backend yields a Secret named secretName with
+// exactly the keys of the masked config fields and auth fields.
+type secretBackend struct {
+	// Name selects the backend with secretBackend.provider in values
+	Name string
+	// Template is rendered into templates/<Template without .tmpl>
+	Template string
+}
+
+// secretBackends are the backends of every generated chart. The first one is the
+// default.
+var secretBackends = []secretBackend{
+	// A Secret created from the values under secret
+	{Name: "native", Template: "secret.yaml.tmpl"},
+	// A Bitnami SealedSecret created from values encrypted with kubeseal
+	{Name: "sealedSecret", Template: "sealedsecret.yaml.tmpl"},
+	// A Vault Secrets Operator VaultStaticSecret syncing a Vault secret
+	{Name: "vault", Template: "vaultstaticsecret.yaml.tmpl"},
+	// A Secrets Store CSI driver SecretProviderClass syncing mounted objects
+	{Name: "csi", Template: "secretproviderclass.yaml.tmpl"},
+}
+
+// secretField is a key of the generated Secret. Masked config fields are always
+// required when they are not optional; auth fields only for their auth section, which
+// the templates check separately.
+type secretField struct {
+	ConfigField
+	RequiredAlways bool
+}
+
+// secretTemplateData is the data passed to the secret backend templates
+type secretTemplateData struct {
+	ChartName                 string
+	IntegrationDefinitionName string
+	SecretFields              []secretField
+	HasMultilineFields        bool
+	AuthSections              []AuthSection
+	AuthSectionIDs            []string
+	// SecretKeys are all keys the Secret may hold, including selectedAuthType
+	SecretKeys []string
+	Backends   []string
+}
+
+func newSecretTemplateData(def IntegrationDefinition) secretTemplateData {
+	data := secretTemplateData{
+		ChartName:                 getChartName(def),
+		IntegrationDefinitionName: def.Name,
+		AuthSections:              getFlattenedAuthSections(def),
+	}
+
+	for _, cf := range getMaskedConfigFields(def) {
+		data.SecretFields = append(data.SecretFields, secretField{ConfigField: cf, RequiredAlways: cf.Required()})
+	}
+	for _, cf := range getAllAuthFields(def) {
+		data.SecretFields = append(data.SecretFields, secretField{ConfigField: cf})
+	}
+	for _, sf := range data.SecretFields {
+		data.SecretKeys = append(data.SecretKeys, sf.Key)
+		data.HasMultilineFields = data.HasMultilineFields || sf.Multiline()
+	}
+
+	for _, as := range data.AuthSections {
+		data.AuthSectionIDs = append(data.AuthSectionIDs, as.ID)
+	}
+	if len(data.AuthSections) > 0 {
+		data.SecretKeys = append(data.SecretKeys, reservedSecretKeys...)
+	}
+
+	for _, backend := range secretBackends {
+		data.Backends = append(data.Backends, backend.Name)
+	}
+	return data
+}
+
+// generateSecretFiles renders the shared secret helpers and the template of every
+// secret backend, keyed by path in the chart
+func generateSecretFiles(def IntegrationDefinition) (map[string]string, error) {
+	data := newSecretTemplateData(def)
+
+	templates := []string{"_secret.tpl.tmpl"}
+	for _, backend := range secretBackends {
+		templates = append(templates, backend.Template)
+	}
+
+	files := make(map[string]string)
+	for _, name := range templates {
+		content, err := renderSecretTemplate(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", strings.TrimSuffix(name, templateSuffix), err)
+		}
+		files[path.Join("templates", strings.TrimSuffix(name, templateSuffix))] = content
+	}
+	return files, nil
+}
+
+func renderSecretTemplate(name string, data secretTemplateData) (string, error) {
+	tmplContent, err := loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+
+	funcMap := template.FuncMap{
+		"join":              strings.Join,
+		"hasRequiredFields": hasRequiredFields,
+		"fieldFormat":       templateFieldFormat,
+		"normalizeFormat":   normalizeFormat,
+	}
+
+	tmpl, err := template.New(name).Funcs(funcMap).Parse(tmplContent)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path"
+)
+
+var (
+	includePatterns []string
+	excludePatterns []string
+)
+
+// integrationSelector decides which fetched integrations are generated. Patterns are
+// globs (path.Match syntax) matched against the integration name, its integration
+// type and its chart name.
+type integrationSelector struct {
+	include       []string
+	includeSource string
+	exclude       []string
+}
+
+// newIntegrationSelector combines the command line and config selections. --include
+// and --name replace the include list from the config; excludes from both are applied.
+func newIntegrationSelector() (integrationSelector, error) {
+	sel := integrationSelector{
+		include:       chartgenConf.Include,
+		includeSource: configPath,
+		exclude:       append(append([]string{}, chartgenConf.Exclude...), excludePatterns...),
+	}
+
+	if len(includePatterns) > 0 || integrationName != "" {
+		sel.include = append([]string{}, includePatterns...)
+		sel.includeSource = "--include"
+		if integrationName != "" {
+			sel.include = append(sel.include, integrationName)
+			sel.includeSource = "--include/--name"
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, sel.include...), sel.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return sel, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return sel, nil
+}
+
+// skipReason returns why an integration is not selected, or "" if it is
+func (s integrationSelector) skipReason(def IntegrationDefinition) string {
+	if len(s.include) > 0 && matchingPattern(s.include, def) == "" {
+		return fmt.Sprintf("not matched by include patterns from %s", s.includeSource)
+	}
+	if pattern := matchingPattern(s.exclude, def); pattern != "" {
+		return fmt.Sprintf("excluded by pattern %q", pattern)
+	}
+	return ""
+}
+
+// matchingPattern returns the first pattern that matches the integration, or ""
+func matchingPattern(patterns []string, def IntegrationDefinition) string {
+	candidates := []string{def.Name, def.IntegrationType, getChartName(def)}
+	for _, pattern := range patterns {
+		for _, candidate := range candidates {
+			if matched, _ := path.Match(pattern, candidate); matched {
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// selectIntegrations applies the collector support check and the selection to every
+// fetched definition, recording skipped definitions in the run report
+func selectIntegrations(definitions []IntegrationDefinition, sel integrationSelector) []IntegrationDefinition {
+	var selected []IntegrationDefinition
+	for _, def := range definitions {
+		reason := ""
+		if !shouldGenerateChart(def) {
+			reason = "does not support collectors"
+		} else {
+			reason = sel.skipReason(def)
+		}
+
+		if reason != "" {
+			if verbose {
+				fmt.Fprintf(progress, "Skipping %s: %s\n", def.Name, reason)
+			}
+			addChartReport(ChartReport{Integration: def.Name, Status: statusSkipped, Reason: reason})
+			continue
+		}
+		selected = append(selected, def)
+	}
+	return selected
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationSelector(t *testing.T) {
+	t.Cleanup(func() {
+		includePatterns, excludePatterns, integrationName = nil, nil, ""
+		chartgenConf = ChartgenConfig{}
+	})
+
+	definitions := []IntegrationDefinition{
+		{Name: "github", IntegrationType: "github", IntegrationPlatformFeatures: IntegrationPlatformFeatures{SupportsCollectors: true}},
+		{Name: "gitlab", IntegrationType: "gitlab", IntegrationPlatformFeatures: IntegrationPlatformFeatures{SupportsCollectors: true}},
+		{Name: "Microsoft_Active_Directory", IntegrationType: "microsoft-ad", IntegrationPlatformFeatures: IntegrationPlatformFeatures{SupportsCollectors: true}},
+		{Name: "jira", IntegrationType: "jira", IntegrationPlatformFeatures: IntegrationPlatformFeatures{SupportsCollectors: true}},
+		{Name: "aws", IntegrationType: "aws"},
+	}
+
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		integration   string
+		configInclude []string
+		configExclude []string
+		want          []string
+		wantErr       string
+	}{
+		{
+			name: "everything that supports collectors",
+			want: []string{"github", "gitlab", "Microsoft_Active_Directory", "jira"},
+		},
+		{
+			name:    "glob on the name",
+			include: []string{"git*"},
+			want:    []string{"github", "gitlab"},
+		},
+		{
+			name:    "glob on the integration type",
+			include: []string{"microsoft-*"},
+			want:    []string{"Microsoft_Active_Directory"},
+		},
+		{
+			name:    "glob on the chart name",
+			include: []string{"microsoft-active-*"},
+			want:    []string{"Microsoft_Active_Directory"},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"git*", "jira"},
+			exclude: []string{"gitlab"},
+			want:    []string{"github", "jira"},
+		},
+		{
+			name:        "name adds to includes",
+			include:     []string{"jira"},
+			integration: "github",
+			want:        []string{"github", "jira"},
+		},
+		{
+			name:    "collector support is still required",
+			include: []string{"aws"},
+		},
+		{
+			name:          "config selection",
+			configInclude: []string{"git*", "jira"},
+			configExclude: []string{"jira"},
+			want:          []string{"github", "gitlab"},
+		},
+		{
+			name:          "command line include replaces config include",
+			include:       []string{"jira"},
+			configInclude: []string{"git*"},
+			want:          []string{"jira"},
+		},
+		{
+			name:          "excludes are combined",
+			exclude:       []string{"github"},
+			configExclude: []string{"jira"},
+			want:          []string{"gitlab", "Microsoft_Active_Directory"},
+		},
+		{
+			name:    "invalid pattern",
+			include: []string{"git["},
+			wantErr: `invalid pattern "git["`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			includePatterns, excludePatterns, integrationName = tt.include, tt.exclude, tt.integration
+			chartgenConf = ChartgenConfig{Include: tt.configInclude, Exclude: tt.configExclude}
+			runReport = RunReport{}
+			t.Cleanup(func() { runReport = RunReport{} })
+
+			sel, err := newIntegrationSelector()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newIntegrationSelector() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newIntegrationSelector() error = %v", err)
+			}
+
+			var got []string
+			for _, def := range selectIntegrations(definitions, sel) {
+				got = append(got, def.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %q, want %q", got, tt.want)
+			}
+			if skipped := len(runReport.Charts); skipped != len(definitions)-len(got) {
+				t.Errorf("%d skipped integrations reported, want %d", skipped, len(definitions)-len(got))
+			}
+		})
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	def := IntegrationDefinition{Name: "gitlab", IntegrationType: "gitlab"}
+
+	tests := []struct {
+		name string
+		sel  integrationSelector
+		want string
+	}{
+		{name: "selected", sel: integrationSelector{include: []string{"git*"}}},
+		{
+			name: "not included",
+			sel:  integrationSelector{include: []string{"jira"}, includeSource: "--include"},
+			want: "not matched by include patterns from --include",
+		},
+		{
+			name: "excluded",
+			sel:  integrationSelector{exclude: []string{"jira", "git*"}},
+			want: `excluded by pattern "git*"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.skipReason(def); got != tt.want {
+				t.Errorf("skipReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	// Name of the umbrella chart that bundles the runner and integrations
+	stackChartName = "jupiterone-stack"
+
+	operatorChartName = "jupiterone-integration-operator"
+	runnerChartName   = "jupiterone-integration-runner"
+
+	// Header written at the top of every file generated by chartgen
+	generatedHeader = "# This file was auto-generated by chartgen. Do not edit manually."
+)
+
+// StackDependency describes a subchart of the umbrella chart
+type StackDependency struct {
+	Name          string
+	Version       string
+	Alias         string
+	Description   string
+	Enabled       bool
+	IsIntegration bool
+}
+
+// ValuesKey returns the key under which the subchart's values live in the umbrella chart
+func (d StackDependency) ValuesKey() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Name
+}
+
+// getStackDependencies returns the runner chart followed by every chartgen-generated
+// integration chart found in the output directory, with versions read from the
+// Chart.yaml files currently on disk.
+//
+// The operator chart is not a dependency: its CRDs are templates, so Helm cannot
+// install them before the IntegrationRunner and IntegrationInstance resources of the
+// same release. It is installed first, in a release of its own.
+func getStackDependencies() ([]StackDependency, error) {
+	if !hasRunnerChart() {
+		return nil, fmt.Errorf("%s chart not found in %s", runnerChartName, outputDir)
+	}
+
+	deps := []StackDependency{{
+		Name:        runnerChartName,
+		Version:     getCurrentChartVersion(runnerChartName),
+		Alias:       "runner",
+		Description: "JupiterOne Integration Runner (requires accountID and apiToken)",
+		Enabled:     true,
+	}}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+
+	var integrations []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == stackChartName || isWorkDir(entry.Name()) {
+			continue
+		}
+		chartYaml := readFileIfExists(filepath.Join(outputDir, entry.Name(), "Chart.yaml"))
+		if strings.HasPrefix(chartYaml, generatedHeader) {
+			integrations = append(integrations, entry.Name())
+		}
+	}
+	sort.Strings(integrations)
+
+	for _, name := range integrations {
+		deps = append(deps, StackDependency{
+			Name:          name,
+			Version:       getCurrentChartVersion(name),
+			Description:   getChartDescription(name),
+			IsIntegration: true,
+		})
+	}
+
+	return deps, nil
+}
+
+// getChartDescription returns the description from an existing Chart.yaml, or the chart name if it has none
+func getChartDescription(chartName string) string {
+	chartYaml := readFileIfExists(filepath.Join(outputDir, chartName, "Chart.yaml"))
+	for _, line := range strings.Split(chartYaml, "\n") {
+		if strings.HasPrefix(line, "description:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "description:"))
+		}
+	}
+	return chartName
+}
+
+// hasRunnerChart returns true if the runner chart exists in the output directory
+func hasRunnerChart() bool {
+	_, err := os.Stat(filepath.Join(outputDir, runnerChartName, "Chart.yaml"))
+	return err == nil
+}
+
+// getStackAppVersion returns the appVersion of the umbrella chart, which is the
+// appVersion of the runner chart it installs
+func getStackAppVersion() string {
+	if version := readChartYamlField(runnerChartName, "appVersion"); version != "" {
+		return version
+	}
+	return defaultAppVersion
+}
+
+// updateStackChart regenerates the umbrella chart if the runner chart exists
+func updateStackChart() error {
+	if !hasRunnerChart() {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s: %s chart not found in %s\n", stackChartName, runnerChartName, outputDir)
+		return nil
+	}
+
+	err, changed := generateStackChart()
+	if err != nil {
+		return err
+	}
+	if changed {
+		fmt.Fprintf(progress, "Successfully generated chart for %s\n", stackChartName)
+	}
+	return nil
+}
+
+// generateStackChart regenerates the jupiterone-stack umbrella chart from the charts
+// currently in the output directory. Returns (error, changed) like generateChart.
+func generateStackChart() (error, bool) {
+	deps, err := getStackDependencies()
+	if err != nil {
+		return err, false
+	}
+
+	if !write {
+		fmt.Fprintf(progress, "[dry-run] Would generate chart: %s\n", stackChartName)
+		for _, dep := range deps {
+			fmt.Fprintf(progress, "    - %s %s\n", dep.Name, dep.Version)
+		}
+		return nil, false
+	}
+
+	chartDir := filepath.Join(outputDir, stackChartName)
+	currentVersion := getCurrentChartVersion(stackChartName)
+
+	files := make(map[string]string)
+
+	chartYaml, err := generateStackChartYaml(deps, currentVersion)
+	if err != nil {
+		return fmt.Errorf("failed to generate Chart.yaml: %w", err), false
+	}
+	files["Chart.yaml"] = chartYaml
+
+	valuesYaml, err := generateStackValuesYaml(deps)
+	if err != nil {
+		return fmt.Errorf("failed to generate values.yaml: %w", err), false
+	}
+	files["values.yaml"] = valuesYaml
+	files[".helmignore"] = helmignore
+
+	if !chartContentChanged(chartDir, files) {
+		if verbose {
+			fmt.Fprintf(progress, "  No changes detected, skipping %s\n", stackChartName)
+		}
+		return nil, false
+	}
+
+	newVersion := bumpPatchVersion(currentVersion)
+	chartYaml, err = generateStackChartYaml(deps, newVersion)
+	if err != nil {
+		return fmt.Errorf("failed to generate Chart.yaml: %w", err), false
+	}
+	files["Chart.yaml"] = chartYaml
+
+	if verbose {
+		fmt.Fprintf(progress, "  Changes detected, bumping version %s -> %s\n", currentVersion, newVersion)
+	}
+
+	if err := writeChart(chartDir, files); err != nil {
+		return err, false
+	}
+
+	return nil, true
+}
+
+func generateStackChartYaml(deps []StackDependency, version string) (string, error) {
+	tmplContent, err := loadTemplate("stack-Chart.yaml.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("stack-chart").Parse(tmplContent)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		Name         string
+		Version      string
+		AppVersion   string
+		Dependencies []StackDependency
+	}{
+		Name:         stackChartName,
+		Version:      version,
+		AppVersion:   getStackAppVersion(),
+		Dependencies: deps,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func generateStackValuesYaml(deps []StackDependency) (string, error) {
+	tmplContent, err := loadTemplate("stack-values.yaml.tmpl")
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("stack-values").Parse(tmplContent)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		Dependencies []StackDependency
+	}{
+		Dependencies: deps,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setupStackTest creates an output directory with the runner chart, two generated
+// integration charts, a hand-written chart and a leftover work directory
+func setupStackTest(t *testing.T) string {
+	t.Helper()
+	dir := setupWriteTest(t, false)
+	savedWrite := write
+	t.Cleanup(func() { write = savedWrite })
+	write = true
+
+	writeTestFiles(t, dir, map[string]string{
+		"jupiterone-integration-runner/Chart.yaml":   "name: jupiterone-integration-runner\nversion: 0.4.2\nappVersion: 0.9.1\n",
+		"jupiterone-integration-operator/Chart.yaml": "name: jupiterone-integration-operator\nversion: 0.3.0\n",
+		"github/Chart.yaml":                          generatedHeader + "\nname: github\ndescription: JupiterOne GitHub integration\nversion: 1.2.3\n",
+		"jira/Chart.yaml":                            generatedHeader + "\nname: jira\nversion: 2.0.0\n",
+		"custom/Chart.yaml":                          "name: custom\nversion: 0.1.0\n",
+		stagingDirPrefix + "github-1234/Chart.yaml":  generatedHeader + "\nname: github\nversion: 1.2.4\n",
+	})
+	return dir
+}
+
+func TestGetStackDependencies(t *testing.T) {
+	setupStackTest(t)
+
+	deps, err := getStackDependencies()
+	if err != nil {
+		t.Fatalf("getStackDependencies() error = %v", err)
+	}
+	want := []StackDependency{
+		{
+			Name: "jupiterone-integration-runner", Version: "0.4.2", Alias: "runner",
+			Description: "JupiterOne Integration Runner (requires accountID and apiToken)", Enabled: true,
+		},
+		{Name: "github", Version: "1.2.3", Description: "JupiterOne GitHub integration", IsIntegration: true},
+		{Name: "jira", Version: "2.0.0", Description: "jira", IsIntegration: true},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("getStackDependencies() = %+v, want %+v", deps, want)
+	}
+	if got := deps[0].ValuesKey(); got != "runner" {
+		t.Errorf("ValuesKey() of the runner = %q, want %q", got, "runner")
+	}
+	if got := deps[1].ValuesKey(); got != "github" {
+		t.Errorf("ValuesKey() of github = %q, want %q", got, "github")
+	}
+}
+
+func TestGetStackDependenciesWithoutRunner(t *testing.T) {
+	dir := setupStackTest(t)
+	if err := os.RemoveAll(filepath.Join(dir, runnerChartName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getStackDependencies(); err == nil {
+		t.Error("getStackDependencies() without the runner chart succeeded")
+	}
+	// updateStackChart skips the umbrella chart with a warning instead
+	if err := updateStackChart(); err != nil {
+		t.Errorf("updateStackChart() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, stackChartName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was written without the runner chart", stackChartName)
+	}
+}
+
+func TestGenerateStackChart(t *testing.T) {
+	dir := setupStackTest(t)
+
+	err, changed := generateStackChart()
+	if err != nil || !changed {
+		t.Fatalf("generateStackChart() = %v, %v, want nil, true", err, changed)
+	}
+	files := readTestFiles(t, filepath.Join(dir, stackChartName))
+	for _, name := range []string{"Chart.yaml", "values.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			assertGolden(t, files[name])
+		})
+	}
+
+	// Nothing changed, so the chart is not written again and keeps its version
+	if err, changed := generateStackChart(); err != nil || changed {
+		t.Fatalf("second generateStackChart() = %v, %v, want nil, false", err, changed)
+	}
+
+	// A new integration chart bumps the version
+	writeTestFiles(t, dir, map[string]string{
+		"netbox/Chart.yaml": generatedHeader + "\nname: netbox\nversion: 1.0.0\n",
+	})
+	if err, changed := generateStackChart(); err != nil || !changed {
+		t.Fatalf("generateStackChart() after adding a chart = %v, %v, want nil, true", err, changed)
+	}
+	if got := getCurrentChartVersion(stackChartName); got != "1.0.2" {
+		t.Errorf("version after adding a chart = %q, want %q", got, "1.0.2")
+	}
+}
+
+func TestRenderStackChart(t *testing.T) {
+	dir := setupWriteTest(t, false)
+	savedWrite := write
+	t.Cleanup(func() { write = savedWrite })
+	write = true
+
+	writeTestFiles(t, dir, map[string]string{
+		runnerChartName + "/Chart.yaml": "apiVersion: v2\nname: " + runnerChartName + "\nversion: 0.4.2\nappVersion: 0.9.1\n",
+	})
+	for _, name := range []string{"alpha", "beta"} {
+		def := testDefinition()
+		def.ID, def.Name = name+"-id", name
+		if err, _ := generateChart(def, &ChartReport{}); err != nil {
+			t.Fatalf("generateChart(%s): %v", name, err)
+		}
+	}
+	if err, _ := generateStackChart(); err != nil {
+		t.Fatalf("generateStackChart: %v", err)
+	}
+
+	// Vendor the dependencies as helm dependency build would
+	stackDir := filepath.Join(dir, stackChartName)
+	for _, name := range []string{runnerChartName, "alpha", "beta"} {
+		if err := os.CopyFS(filepath.Join(stackDir, "charts", name), os.DirFS(filepath.Join(dir, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	integration := `
+  enabled: true
+  organization: acme
+  secret:
+    selectedAuthType: token
+    apiToken: token`
+	out := renderTestChart(t, stackDir, "runner:\n  enabled: false\nalpha:"+integration+"\nbeta:"+integration+"\n")
+	assertGolden(t, out)
+
+	// The integrations of one release must not create objects of the same name
+	seen := map[string]bool{}
+	dec := yaml.NewDecoder(strings.NewReader(strings.ReplaceAll(out, "# Source: ", "---\n# Source: ")))
+	for {
+		var obj struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := dec.Decode(&obj); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to parse manifests: %v", err)
+		}
+		if obj.Kind == "" {
+			continue
+		}
+		key := obj.Kind + "/" + obj.Metadata.Name
+		if seen[key] {
+			t.Errorf("duplicate object %s", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) == 0 {
+		t.Error("no objects rendered")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffix of chartgen template files, stripped from extra templates when they are written
+const templateSuffix = ".tmpl"
+
+// templateOverridesDir optionally overlays the embedded templates. Files with the same name as
+// an embedded template replace it; any other *.tmpl file is an extra template rendered
+// into the templates/ directory of every integration chart.
+var templateOverridesDir string
+
+// loadTemplate returns the named template from templateOverridesDir if present, otherwise the embedded copy
+func loadTemplate(name string) (string, error) {
+	if templateOverridesDir != "" {
+		content, err := os.ReadFile(filepath.Join(templateOverridesDir, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+	}
+
+	content, err := templateFS.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// isEmbeddedTemplate returns true if chartgen ships a template with this name
+func isEmbeddedTemplate(name string) bool {
+	_, err := fs.Stat(templateFS, "templates/"+name)
+	return err == nil
+}
+
+// getTemplateOverrides lists the *.tmpl files in templateOverridesDir (relative, slash-separated),
+// split into overrides of embedded templates and extra templates.
+func getTemplateOverrides() (overrides []string, extras []string, err error) {
+	if templateOverridesDir == "" {
+		return nil, nil, nil
+	}
+
+	err = filepath.WalkDir(templateOverridesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), templateSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(templateOverridesDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if isEmbeddedTemplate(rel) {
+			overrides = append(overrides, rel)
+		} else {
+			extras = append(extras, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read templates directory %s: %w", templateOverridesDir, err)
+	}
+
+	sort.Strings(overrides)
+	sort.Strings(extras)
+	return overrides, extras, nil
+}
+
+// reportTemplateOverrides validates templateOverridesDir and prints which templates it provides
+func reportTemplateOverrides() error {
+	if templateOverridesDir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(templateOverridesDir)
+	if err != nil {
+		return fmt.Errorf("failed to open templates directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("templates directory %s is not a directory", templateOverridesDir)
+	}
+
+	overrides, extras, err := getTemplateOverrides()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(progress, "Using templates from %s\n", templateOverridesDir)
+	for _, name := range overrides {
+		fmt.Fprintf(progress, "  - %s (overrides built-in template)\n", name)
+	}
+	for _, name := range extras {
+		fmt.Fprintf(progress, "  - %s (extra template, rendered as templates/%s)\n", name, strings.TrimSuffix(name, templateSuffix))
+	}
+	if len(overrides) == 0 && len(extras) == 0 {
+		fmt.Fprintln(progress, "  (no *.tmpl files found, using built-in templates)")
+	}
+	return nil
+}
+
+// extraTemplateData is the data passed to extra templates
+type extraTemplateData struct {
+	chartTemplateData
+	Definition         IntegrationDefinition
+	ConfigFields       []ConfigField
+	MaskedConfigFields []ConfigField
+	HasSecretFields    bool
+}
+
+// generateExtraTemplates renders every extra template in templateOverridesDir for the given
+// integration. The result is keyed by the path of the file within the chart.
+func generateExtraTemplates(def IntegrationDefinition) (map[string]string, error) {
+	_, extras, err := getTemplateOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	data := extraTemplateData{
+		chartTemplateData:  newChartTemplateData(def),
+		Definition:         def,
+		ConfigFields:       getNonMaskedConfigFields(def),
+		MaskedConfigFields: getMaskedConfigFields(def),
+		HasSecretFields:    hasSecretFields(def),
+	}
+
+	files := make(map[string]string)
+	for _, name := range extras {
+		content, err := renderTemplate(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render extra template %s: %w", name, err)
+		}
+		files["templates/"+strings.TrimSuffix(name, templateSuffix)] = content
+	}
+	return files, nil
+}
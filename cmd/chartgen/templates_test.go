@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupTemplatesDir writes templates to a temporary --templates-dir
+func setupTemplatesDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	saved := templateOverridesDir
+	t.Cleanup(func() { templateOverridesDir = saved })
+	templateOverridesDir = t.TempDir()
+	writeTestFiles(t, templateOverridesDir, files)
+	return templateOverridesDir
+}
+
+func TestGetTemplateOverrides(t *testing.T) {
+	setupTemplatesDir(t, map[string]string{
+		"secret.yaml.tmpl":            "",
+		"NOTES.txt.tmpl":              "",
+		"configmap.yaml.tmpl":         "",
+		"policies/network.yaml.tmpl":  "",
+		"README-overrides.md":         "",
+		"policies/network.yaml.notes": "",
+	})
+
+	overrides, extras, err := getTemplateOverrides()
+	if err != nil {
+		t.Fatalf("getTemplateOverrides() error = %v", err)
+	}
+	if want := []string{"NOTES.txt.tmpl", "secret.yaml.tmpl"}; !reflect.DeepEqual(overrides, want) {
+		t.Errorf("overrides = %q, want %q", overrides, want)
+	}
+	if want := []string{"configmap.yaml.tmpl", "policies/network.yaml.tmpl"}; !reflect.DeepEqual(extras, want) {
+		t.Errorf("extras = %q, want %q", extras, want)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	setupTemplatesDir(t, map[string]string{
+		"NOTES.txt.tmpl": "custom notes\n",
+	})
+
+	if got, err := loadTemplate("NOTES.txt.tmpl"); err != nil || got != "custom notes\n" {
+		t.Errorf("loadTemplate() of an override = %q, %v, want the override", got, err)
+	}
+	embedded, err := templateFS.ReadFile("templates/secret.yaml.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadTemplate("secret.yaml.tmpl"); err != nil || got != string(embedded) {
+		t.Errorf("loadTemplate() without an override = %q, %v, want the embedded template", got, err)
+	}
+	if _, err := loadTemplate("missing.yaml.tmpl"); err == nil {
+		t.Error("loadTemplate() of a missing template succeeded")
+	}
+}
+
+func TestReportTemplateOverrides(t *testing.T) {
+	dir := setupTemplatesDir(t, map[string]string{
+		"NOTES.txt.tmpl":      "",
+		"configmap.yaml.tmpl": "",
+	})
+
+	var out strings.Builder
+	saved := progress
+	t.Cleanup(func() { progress = saved })
+	progress = &out
+
+	if err := reportTemplateOverrides(); err != nil {
+		t.Fatalf("reportTemplateOverrides() error = %v", err)
+	}
+	want := "Using templates from " + dir + "\n" +
+		"  - NOTES.txt.tmpl (overrides built-in template)\n" +
+		"  - configmap.yaml.tmpl (extra template, rendered as templates/configmap.yaml)\n"
+	if out.String() != want {
+		t.Errorf("reportTemplateOverrides() printed %q, want %q", out.String(), want)
+	}
+
+	progress = io.Discard
+	templateOverridesDir = filepath.Join(dir, "NOTES.txt.tmpl")
+	if err := reportTemplateOverrides(); err == nil {
+		t.Error("reportTemplateOverrides() of a file succeeded")
+	}
+}
+
+func TestRenderTemplateOverrides(t *testing.T) {
+	setupTemplatesDir(t, map[string]string{
+		// Overrides of chart templates replace the built-in ones
+		"NOTES.txt.tmpl": `{{ .Title }} is installed in {{ "{{ .Release.Namespace }}" }}.
+`,
+		// Extra templates are rendered with the chart's name, definition and fields
+		"configmap.yaml.tmpl": `{{ "{{-" }} if .Values.organization {{ "}}" }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ "{{" }} include "{{ .ChartName }}.fullname" . {{ "}}" }}-fields
+  labels:
+    {{ "{{-" }} include "{{ .ChartName }}.labels" . | nindent 4 {{ "}}" }}
+data:
+  definition: {{ .Definition.Name }}
+  fields: "{{ range .ConfigFields }}{{ .Key }} {{ end }}"
+  secret: "{{ .HasSecretFields }}"
+{{ "{{-" }} end {{ "}}" }}
+`,
+	})
+
+	chartDir := generateTestChart(t, testDefinition())
+	values := "organization: acme\nsecret:\n  apiToken: token\n"
+	assertGolden(t, renderTestChart(t, chartDir, values, "NOTES.txt", "configmap.yaml"))
+}
+
+func TestGenerateChartWithInvalidExtraTemplate(t *testing.T) {
+	setupTemplatesDir(t, map[string]string{
+		"configmap.yaml.tmpl": "{{ .Missing }}\n",
+	})
+
+	savedOutputDir, savedWrite, savedProgress := outputDir, write, progress
+	t.Cleanup(func() {
+		outputDir, write, progress = savedOutputDir, savedWrite, savedProgress
+	})
+	outputDir, write, progress = t.TempDir(), true, io.Discard
+
+	err, _ := generateChart(testDefinition(), &ChartReport{})
+	if err == nil || !strings.Contains(err.Error(), "extra template configmap.yaml.tmpl") {
+		t.Errorf("generateChart() error = %v, want an error about configmap.yaml.tmpl", err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFormatField(t *testing.T) {
+	tests := []struct {
+		name      string
+		indent    string
+		commented bool
+		field     ConfigField
+		want      string
+	}{
+		{
+			name:  "optional without default",
+			field: ConfigField{Key: "host", Type: "string", Optional: true},
+			want:  "host:",
+		},
+		{
+			name:  "required string placeholder",
+			field: ConfigField{Key: "host", Type: "string"},
+			want:  `host: ""  # required`,
+		},
+		{
+			name:  "required number placeholder",
+			field: ConfigField{Key: "pages", Type: "number"},
+			want:  "pages: null  # required",
+		},
+		{
+			name:  "required string with a format placeholder",
+			field: ConfigField{Key: "url", Type: "string", Format: "url"},
+			want:  "url: null  # required",
+		},
+		{
+			name:  "required with default",
+			field: ConfigField{Key: "host", Type: "string", DefaultValue: "localhost"},
+			want:  `host: "localhost"`,
+		},
+		{
+			name:  "false",
+			field: ConfigField{Key: "verify", Type: "boolean", Optional: true, DefaultValue: false},
+			want:  "verify: false",
+		},
+		{
+			name:  "zero",
+			field: ConfigField{Key: "pages", Type: "number", Optional: true, DefaultValue: json.Number("0")},
+			want:  "pages: 0",
+		},
+		{
+			name:  "float keeps its precision",
+			field: ConfigField{Key: "ratio", Type: "number", Optional: true, DefaultValue: json.Number("1.50")},
+			want:  "ratio: 1.50",
+		},
+		{
+			name:  "integer beyond int64",
+			field: ConfigField{Key: "id", Type: "number", Optional: true, DefaultValue: json.Number("12345678901234567890")},
+			want:  "id: 12345678901234567890",
+		},
+		{
+			name:  "numeric string stays a string",
+			field: ConfigField{Key: "days", Type: "string", Optional: true, DefaultValue: "90"},
+			want:  `days: "90"`,
+		},
+		{
+			name:  "boolean-like string stays a string",
+			field: ConfigField{Key: "answer", Type: "string", Optional: true, DefaultValue: "yes"},
+			want:  `answer: "yes"`,
+		},
+		{
+			name:  "multi-line string",
+			field: ConfigField{Key: "query", Type: "string", Optional: true, DefaultValue: "FIND host\nRETURN host.name"},
+			want:  "query: |-\n  FIND host\n  RETURN host.name",
+		},
+		{
+			name:  "list",
+			field: ConfigField{Key: "states", Type: "string", Optional: true, DefaultValue: []any{"open", json.Number("2")}},
+			want:  "states:\n  - \"open\"\n  - 2",
+		},
+		{
+			name:  "empty list",
+			field: ConfigField{Key: "states", Type: "string", Optional: true, DefaultValue: []any{}},
+			want:  "states: []",
+		},
+		{
+			name:  "multi-select without default",
+			field: ConfigField{Key: "states", Type: "multiselect", Optional: true},
+			want:  "states: []",
+		},
+		{
+			name:  "map with sorted keys",
+			field: ConfigField{Key: "limits", Type: "object", Optional: true, DefaultValue: map[string]any{"b": true, "a": json.Number("1")}},
+			want:  "limits:\n  a: 1\n  b: true",
+		},
+		{
+			name:  "default from the config file",
+			field: ConfigField{Key: "pages", Type: "number", Optional: true, DefaultValue: 5},
+			want:  "pages: 5",
+		},
+		{
+			name:      "commented and indented",
+			indent:    "  ",
+			commented: true,
+			field:     ConfigField{Key: "states", Type: "string", Optional: true, DefaultValue: []any{"open"}},
+			want:      "  # states:\n  #   - \"open\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatField(tt.indent, tt.commented, tt.field)
+			if err != nil {
+				t.Fatalf("formatField() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatField() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueToNodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  any // the value read back from YAML
+	}{
+		{name: "int", value: json.Number("42"), want: 42},
+		{name: "negative", value: json.Number("-7"), want: -7},
+		{name: "float", value: json.Number("1.5"), want: 1.5},
+		{name: "exponent", value: json.Number("1e3"), want: 1000.0},
+		{name: "large integer", value: json.Number("12345678901234567890"), want: uint64(12345678901234567890)},
+		{name: "bool", value: false, want: false},
+		{name: "numeric string", value: "007", want: "007"},
+		{name: "null-like string", value: "null", want: "null"},
+		{name: "empty string", value: "", want: ""},
+		{name: "multi-line string", value: "a\nb\n", want: "a\nb\n"},
+		{name: "list", value: []any{"a", json.Number("1"), true}, want: []any{"a", 1, true}},
+		{name: "map", value: map[string]any{"on": "off"}, want: map[string]any{"on": "off"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := valueToNode(tt.value)
+			if err != nil {
+				t.Fatalf("valueToNode() error = %v", err)
+			}
+			out, err := yaml.Marshal(node)
+			if err != nil {
+				t.Fatalf("failed to encode node: %v", err)
+			}
+
+			var got any
+			if err := yaml.Unmarshal(out, &got); err != nil {
+				t.Fatalf("failed to decode %q: %v", out, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q read back as %#v, want %#v", out, got, tt.want)
+			}
+		})
+	}
+}
+
+// configTypesDefinition returns an integration with boolean, number, multi-select and
+// single-select config fields
+func configTypesDefinition() IntegrationDefinition {
+	def := testDefinition()
+	def.ConfigFields = []ConfigField{
+		{Key: "verifySsl", DisplayName: "Verify SSL", Type: "boolean", Optional: true, DefaultValue: false},
+		{Key: "pageSize", DisplayName: "Page size", Type: "number", Optional: true, DefaultValue: json.Number("0")},
+		{
+			Key: "states", DisplayName: "States", Type: "multiselect", Optional: true,
+			Options:      []ConfigOption{{Label: "Open", Value: "open"}, {Label: "Closed", Value: "closed"}},
+			DefaultValue: []any{"open"},
+		},
+		{
+			Key: "region", DisplayName: "Region", Type: "string",
+			Options: []ConfigOption{{Label: "United States", Value: "us"}, {Label: "Europe", Value: "eu"}},
+		},
+		{
+			Key: "retries", DisplayName: "Retries", Type: "number", Optional: true,
+			Options: []ConfigOption{{Value: "1"}, {Value: "3"}},
+		},
+	}
+	return def
+}
+
+func TestRenderConfigValues(t *testing.T) {
+	chartDir := generateTestChart(t, configTypesDefinition())
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name: "defaults",
+			values: `
+region: us
+`,
+		},
+		{
+			name: "overrides",
+			values: `
+region: eu
+verifySsl: true
+pageSize: 250
+states: [open, closed]
+retries: 3
+`,
+		},
+		{
+			name: "option as a string",
+			values: `
+region: us
+retries: "1"
+`,
+		},
+		{
+			name: "unknown option",
+			values: `
+region: asia
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.values + "secret:\n  selectedAuthType: token\n  apiToken: token\n"
+			assertGolden(t, renderTestChart(t, chartDir, values, "integrationinstance.yaml"))
+		})
+	}
+}
+
+func TestRenderRequiredFields(t *testing.T) {
+	def := testDefinition()
+	def.ConfigFields = append(def.ConfigFields, ConfigField{Key: "baseUrl", Type: "string", DefaultValue: "https://api.example.com"})
+	chartDir := generateTestChart(t, def)
+
+	tests := []struct {
+		name   string
+		values string
+	}{
+		{
+			name: "all set",
+			values: `
+organization: acme
+secret:
+  selectedAuthType: token
+  apiToken: token
+`,
+		},
+		{
+			name: "config field missing",
+			values: `
+secret:
+  selectedAuthType: token
+  apiToken: token
+`,
+		},
+		{
+			name: "auth field missing",
+			values: `
+organization: acme
+secret:
+  selectedAuthType: token
+`,
+		},
+		{
+			name: "required field with default cleared",
+			values: `
+organization: acme
+baseUrl: ""
+secret:
+  selectedAuthType: token
+  apiToken: token
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, renderTestChart(t, chartDir, tt.values, "integrationinstance.yaml"))
+		})
+	}
+}
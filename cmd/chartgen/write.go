@@ -0,0 +1,318 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Prefixes of the hidden directories used while writing charts. They are created next
+// to the chart in the output directory so that the final rename stays on one filesystem.
+const (
+	stagingDirPrefix = ".chartgen-staging-"
+	backupDirPrefix  = ".chartgen-backup-"
+)
+
+// rollbackOnFailure keeps the previous version of every chart written in this run and
+// restores them all if any chart fails
+var rollbackOnFailure bool
+
+// writtenChart records a chart replaced in this run and where its previous version is kept
+type writtenChart struct {
+	chartDir  string
+	backupDir string // empty if the chart did not exist before
+}
+
+var writtenCharts []writtenChart
+
+// errExchangeUnsupported is returned by exchangeDirs when directories cannot be swapped
+// atomically, in which case writeChart falls back to two renames
+var errExchangeUnsupported = errors.New("atomic directory exchange is not supported")
+
+// generatedChartPaths returns the paths of every file chartgen may write to a chart,
+// relative to the chart directory. Those that are not generated any more are dropped
+// when the chart is rewritten.
+func generatedChartPaths() []string {
+	paths := []string{
+		".helmignore",
+		"Chart.yaml",
+		"README.md",
+		"values.schema.json",
+		"values.yaml",
+		"templates/NOTES.txt",
+		"templates/_helpers.tpl",
+		"templates/_secret.tpl",
+		"templates/integrationinstance.yaml",
+		"templates/runner.yaml",
+		"templates/tests/integrationinstance-test.yaml",
+	}
+	for _, backend := range secretBackends {
+		paths = append(paths, path.Join("templates", strings.TrimSuffix(backend.Template, templateSuffix)))
+	}
+	return paths
+}
+
+// writeChart writes the files of a chart atomically: the generated files are written to
+// a staging directory together with the files of the existing chart that chartgen did
+// not generate, and the staging directory is then swapped in place of the chart. Files
+// that chartgen generated before but no longer does, including those rendered from
+// templates that start with generatedHeader, are dropped. If anything fails, the
+// existing chart is left untouched.
+func writeChart(chartDir string, files map[string]string) error {
+	parent := filepath.Dir(chartDir)
+	name := filepath.Base(chartDir)
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	stagingDir, err := os.MkdirTemp(parent, stagingDirPrefix+name+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	exists := true
+	if _, err := os.Stat(chartDir); os.IsNotExist(err) {
+		exists = false
+	} else if err != nil {
+		return fmt.Errorf("failed to read chart directory: %w", err)
+	}
+
+	if exists {
+		if err := stagePreservedFiles(chartDir, stagingDir, files); err != nil {
+			return fmt.Errorf("failed to stage existing chart: %w", err)
+		}
+	}
+
+	for relPath, content := range files {
+		fullPath := filepath.Join(stagingDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", relPath, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", relPath, err)
+		}
+	}
+
+	written := writtenChart{chartDir: chartDir}
+	if exists {
+		written.backupDir = filepath.Join(parent, backupDirPrefix+name)
+		if err := os.RemoveAll(written.backupDir); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		if err := swapChart(stagingDir, chartDir, written.backupDir); err != nil {
+			return err
+		}
+	} else if err := os.Rename(stagingDir, chartDir); err != nil {
+		return fmt.Errorf("failed to move new chart in place: %w", err)
+	}
+
+	if rollbackOnFailure {
+		writtenCharts = append(writtenCharts, written)
+		return nil
+	}
+
+	if exists {
+		if err := os.RemoveAll(written.backupDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove backup %s: %v\n", written.backupDir, err)
+		}
+	}
+	return nil
+}
+
+// swapChart replaces chartDir with stagingDir and moves the previous chart to backupDir.
+// Where the filesystem supports it the two directories are exchanged atomically, so the
+// chart never disappears; otherwise the chart is moved to backupDir first, and an
+// interrupted run leaves it there for recoverWorkDirs to restore.
+func swapChart(stagingDir string, chartDir string, backupDir string) error {
+	err := exchangeDirs(stagingDir, chartDir)
+	if err == nil {
+		if err := os.Rename(stagingDir, backupDir); err != nil {
+			if restoreErr := exchangeDirs(stagingDir, chartDir); restoreErr != nil {
+				return fmt.Errorf("failed to keep previous chart: %w (previous chart left in %s: %v)", err, stagingDir, restoreErr)
+			}
+			return fmt.Errorf("failed to keep previous chart: %w", err)
+		}
+		return nil
+	}
+	if !errors.Is(err, errExchangeUnsupported) {
+		return fmt.Errorf("failed to swap in new chart: %w", err)
+	}
+
+	if err := os.Rename(chartDir, backupDir); err != nil {
+		return fmt.Errorf("failed to move existing chart aside: %w", err)
+	}
+	if err := os.Rename(stagingDir, chartDir); err != nil {
+		if restoreErr := os.Rename(backupDir, chartDir); restoreErr != nil {
+			return fmt.Errorf("failed to swap in new chart: %w (previous chart left in %s: %v)", err, backupDir, restoreErr)
+		}
+		return fmt.Errorf("failed to swap in new chart: %w", err)
+	}
+	return nil
+}
+
+// stagePreservedFiles copies the files of an existing chart that chartgen does not
+// generate into the staging directory. Generated files, whether or not they are still
+// part of files, are left out.
+func stagePreservedFiles(chartDir string, stagingDir string, files map[string]string) error {
+	generated := make(map[string]bool)
+	for _, relPath := range generatedChartPaths() {
+		generated[relPath] = true
+	}
+	for relPath := range files {
+		generated[relPath] = true
+	}
+
+	return filepath.WalkDir(chartDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(chartDir, path)
+		if err != nil {
+			return err
+		}
+		if generated[filepath.ToSlash(rel)] {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("unsupported file type: %s", path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(string(content), generatedHeader) {
+			if verbose {
+				fmt.Fprintf(progress, "  Removing %s, which is no longer generated\n", filepath.ToSlash(rel))
+			}
+			return nil
+		}
+		return copyFile(path, filepath.Join(stagingDir, rel))
+	})
+}
+
+// commitChartWrites removes the backups kept for rollbackOnFailure
+func commitChartWrites() {
+	for _, written := range writtenCharts {
+		if written.backupDir == "" {
+			continue
+		}
+		if err := os.RemoveAll(written.backupDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove backup %s: %v\n", written.backupDir, err)
+		}
+	}
+	writtenCharts = nil
+}
+
+// rollbackChartWrites restores every chart written in this run to its previous version,
+// in reverse order, and removes charts that did not exist before
+func rollbackChartWrites() error {
+	var failed []string
+	for i := len(writtenCharts) - 1; i >= 0; i-- {
+		written := writtenCharts[i]
+		if err := os.RemoveAll(written.chartDir); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", written.chartDir, err))
+			continue
+		}
+		if written.backupDir == "" {
+			continue
+		}
+		if err := os.Rename(written.backupDir, written.chartDir); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v (previous chart left in %s)", written.chartDir, err, written.backupDir))
+		}
+	}
+
+	restored := len(writtenCharts)
+	writtenCharts = nil
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to roll back %d of %d charts:\n  %s", len(failed), restored, strings.Join(failed, "\n  "))
+	}
+	fmt.Fprintf(progress, "Rolled back %d charts\n", restored)
+	return nil
+}
+
+// isWorkDir returns true for the staging and backup directories used by writeChart
+func isWorkDir(name string) bool {
+	return strings.HasPrefix(name, stagingDirPrefix) || strings.HasPrefix(name, backupDirPrefix)
+}
+
+// recoverWorkDirs cleans up the staging and backup directories left in the output
+// directory by an interrupted run. Staging directories are removed. A backup whose chart
+// directory is missing holds the only copy of that chart and is moved back; other
+// backups are removed.
+func recoverWorkDirs() {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !isWorkDir(name) {
+			continue
+		}
+		workDir := filepath.Join(outputDir, name)
+
+		if chartName, ok := strings.CutPrefix(name, backupDirPrefix); ok {
+			chartDir := filepath.Join(outputDir, chartName)
+			if _, err := os.Stat(chartDir); os.IsNotExist(err) {
+				if err := os.Rename(workDir, chartDir); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to restore %s from %s: %v\n", chartDir, workDir, err)
+				} else {
+					fmt.Fprintf(os.Stderr, "Restored %s from %s left by an interrupted run\n", chartDir, name)
+				}
+				continue
+			}
+		}
+
+		if err := os.RemoveAll(workDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s left by an interrupted run: %v\n", workDir, err)
+		} else if verbose {
+			fmt.Fprintf(progress, "Removed %s left by an interrupted run\n", workDir)
+		}
+	}
+}
+
+// copyFile copies a regular file, preserving its permissions and creating the
+// directories above it
+func copyFile(src string, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// exchangeDirs atomically swaps two directories. It returns errExchangeUnsupported when
+// the filesystem cannot exchange them.
+func exchangeDirs(a string, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return errExchangeUnsupported
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// exchangeDirs is only implemented on Linux; elsewhere charts are swapped with two renames
+func exchangeDirs(a string, b string) error {
+	return errExchangeUnsupported
+}
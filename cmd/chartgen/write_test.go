@@ -0,0 +1,232 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTestFiles writes files relative to dir
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		path := filepath.Join(dir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTestFiles returns the files under dir, keyed by slash-separated relative path
+func readTestFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// dirNames returns the sorted names of the entries of dir
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setupWriteTest points outputDir at a temporary directory for the test and restores
+// the write state afterwards
+func setupWriteTest(t *testing.T, rollback bool) string {
+	t.Helper()
+	savedOutputDir, savedRollback, savedProgress := outputDir, rollbackOnFailure, progress
+	t.Cleanup(func() {
+		outputDir, rollbackOnFailure, progress = savedOutputDir, savedRollback, savedProgress
+		writtenCharts = nil
+	})
+	outputDir = t.TempDir()
+	rollbackOnFailure = rollback
+	progress = io.Discard
+	writtenCharts = nil
+	return outputDir
+}
+
+func TestWriteChart(t *testing.T) {
+	generated := generatedHeader + "\n"
+
+	tests := []struct {
+		name     string
+		existing map[string]string // nil when the chart does not exist
+		files    map[string]string
+		want     map[string]string
+	}{
+		{
+			name:  "new chart",
+			files: map[string]string{"Chart.yaml": "version: 1.0.0\n", "templates/integrationinstance.yaml": generated},
+			want:  map[string]string{"Chart.yaml": "version: 1.0.0\n", "templates/integrationinstance.yaml": generated},
+		},
+		{
+			name: "generated files replaced",
+			existing: map[string]string{
+				"Chart.yaml":  "version: 1.0.0\n",
+				"values.yaml": "old: true\n",
+			},
+			files: map[string]string{"Chart.yaml": "version: 1.0.1\n", "values.yaml": "new: true\n"},
+			want:  map[string]string{"Chart.yaml": "version: 1.0.1\n", "values.yaml": "new: true\n"},
+		},
+		{
+			name: "stale generated files dropped",
+			existing: map[string]string{
+				"Chart.yaml":                 "version: 1.0.0\n",
+				"values.schema.json":         "{}\n",
+				"templates/secret.yaml":      generated + "kind: Secret\n",
+				"templates/_secret.tpl":      generated,
+				"templates/from-extras.yaml": generated + "kind: ConfigMap\n",
+			},
+			files: map[string]string{"Chart.yaml": "version: 1.0.1\n"},
+			want:  map[string]string{"Chart.yaml": "version: 1.0.1\n"},
+		},
+		{
+			name: "hand-written files kept",
+			existing: map[string]string{
+				"Chart.yaml":                   "version: 1.0.0\n",
+				"templates/networkpolicy.yaml": "kind: NetworkPolicy\n",
+				"ci/test-values.yaml":          "organization: acme\n",
+			},
+			files: map[string]string{"Chart.yaml": "version: 1.0.1\n"},
+			want: map[string]string{
+				"Chart.yaml":                   "version: 1.0.1\n",
+				"templates/networkpolicy.yaml": "kind: NetworkPolicy\n",
+				"ci/test-values.yaml":          "organization: acme\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupWriteTest(t, false)
+			chartDir := filepath.Join(dir, "example")
+			if tt.existing != nil {
+				writeTestFiles(t, chartDir, tt.existing)
+			}
+
+			if err := writeChart(chartDir, tt.files); err != nil {
+				t.Fatalf("writeChart() error = %v", err)
+			}
+
+			if got := readTestFiles(t, chartDir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chart files = %q, want %q", got, tt.want)
+			}
+			if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"example"}) {
+				t.Errorf("output directory = %q, want only the chart", got)
+			}
+		})
+	}
+}
+
+func TestRollbackChartWrites(t *testing.T) {
+	dir := setupWriteTest(t, true)
+	existingDir := filepath.Join(dir, "existing")
+	newDir := filepath.Join(dir, "new")
+	previous := map[string]string{"Chart.yaml": "version: 1.0.0\n", "templates/custom.yaml": "kind: ConfigMap\n"}
+	writeTestFiles(t, existingDir, previous)
+
+	if err := writeChart(existingDir, map[string]string{"Chart.yaml": "version: 1.0.1\n"}); err != nil {
+		t.Fatalf("writeChart(existing) error = %v", err)
+	}
+	if err := writeChart(newDir, map[string]string{"Chart.yaml": "version: 1.0.0\n"}); err != nil {
+		t.Fatalf("writeChart(new) error = %v", err)
+	}
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{".chartgen-backup-existing", "existing", "new"}) {
+		t.Fatalf("output directory before rollback = %q", got)
+	}
+
+	if err := rollbackChartWrites(); err != nil {
+		t.Fatalf("rollbackChartWrites() error = %v", err)
+	}
+
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"existing"}) {
+		t.Errorf("output directory after rollback = %q, want only the existing chart", got)
+	}
+	if got := readTestFiles(t, existingDir); !reflect.DeepEqual(got, previous) {
+		t.Errorf("restored chart = %q, want %q", got, previous)
+	}
+}
+
+func TestCommitChartWrites(t *testing.T) {
+	dir := setupWriteTest(t, true)
+	chartDir := filepath.Join(dir, "example")
+	writeTestFiles(t, chartDir, map[string]string{"Chart.yaml": "version: 1.0.0\n"})
+
+	if err := writeChart(chartDir, map[string]string{"Chart.yaml": "version: 1.0.1\n"}); err != nil {
+		t.Fatalf("writeChart() error = %v", err)
+	}
+	commitChartWrites()
+
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"example"}) {
+		t.Errorf("output directory = %q, want the backup removed", got)
+	}
+	if got := readTestFiles(t, chartDir)["Chart.yaml"]; got != "version: 1.0.1\n" {
+		t.Errorf("Chart.yaml = %q, want the new version", got)
+	}
+	if len(writtenCharts) != 0 {
+		t.Errorf("writtenCharts = %v, want it cleared", writtenCharts)
+	}
+}
+
+func TestRecoverWorkDirs(t *testing.T) {
+	dir := setupWriteTest(t, false)
+	writeTestFiles(t, dir, map[string]string{
+		// Interrupted while staging: the chart is intact
+		"staged/Chart.yaml":                       "version: 1.0.0\n",
+		".chartgen-staging-staged-123/Chart.yaml": "version: 1.0.1\n",
+		// Interrupted after the swap: the backup is no longer needed
+		"swapped/Chart.yaml":                  "version: 1.0.1\n",
+		".chartgen-backup-swapped/Chart.yaml": "version: 1.0.0\n",
+		// Interrupted between the two renames: the backup is the only copy
+		".chartgen-backup-moved/Chart.yaml":      "version: 1.0.0\n",
+		".chartgen-staging-moved-456/Chart.yaml": "version: 1.0.1\n",
+	})
+
+	recoverWorkDirs()
+
+	if got := dirNames(t, dir); !reflect.DeepEqual(got, []string{"moved", "staged", "swapped"}) {
+		t.Errorf("output directory = %q, want only the charts", got)
+	}
+	want := map[string]string{
+		"moved/Chart.yaml":   "version: 1.0.0\n",
+		"staged/Chart.yaml":  "version: 1.0.0\n",
+		"swapped/Chart.yaml": "version: 1.0.1\n",
+	}
+	if got := readTestFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("charts = %q, want %q", got, want)
+	}
+}